@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pmacct/processor/internal/admin"
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/diag"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/remoteconfig"
+)
+
+var (
+	// logLevelVar 运行时可调整的日志级别
+	logLevelVar = new(slog.LevelVar)
+	// logRing 最近的告警/错误日志（/status 展示）
+	logRing = admin.NewLogRing(50)
+	// lastInputAt 最近一次从 stdin 读到数据的时间（UnixNano）
+	lastInputAt atomic.Int64
+)
+
+// FTP 可达性检查在后台按固定间隔执行，/readyz 只读取最近一次结果，避免探针频繁登录 FTP 或被慢连接阻塞
+const (
+	reachCheckInterval = 30 * time.Second
+	reachCheckTimeout  = 10 * time.Second
+)
+
+// startAdmin 启动管理接口（未启用时直接返回）
+func startAdmin(ctx context.Context, cfg *config.ProcessorConfig, dataDir string, pipes pipelineSet, diagCollector *diag.Collector, fetcher *remoteconfig.Fetcher, flowProc *flowProcessor) {
+	srv := admin.NewServer(cfg.Admin)
+	if srv == nil {
+		return
+	}
+	startTime := time.Now()
+	bw, up := pipes[0].bw, pipes[0].up
+
+	srv.AddReadyCheck("data_dir_writable", func() error {
+		return checkDirWritable(dataDir)
+	})
+	var reachErr atomic.Pointer[error]
+	go func() {
+		ticker := time.NewTicker(reachCheckInterval)
+		defer ticker.Stop()
+		for {
+			err := up.CheckReachable(reachCheckTimeout)
+			reachErr.Store(&err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	srv.AddReadyCheck("ftp_reachable", func() error {
+		p := reachErr.Load()
+		if p == nil {
+			return errors.New("FTP 可达性检查尚未完成")
+		}
+		return *p
+	})
+	stale := time.Duration(cfg.Admin.InputStaleSec) * time.Second
+	srv.AddReadyCheck("input_flowing", func() error {
+		last := lastInputAt.Load()
+		if last == 0 {
+			if time.Since(startTime) < stale {
+				return nil
+			}
+			return fmt.Errorf("启动后 %s 内未收到输入", stale)
+		}
+		if idle := time.Since(time.Unix(0, last)); idle > stale {
+			return fmt.Errorf("已 %s 未收到输入", idle.Truncate(time.Second))
+		}
+		return nil
+	})
+
+	srv.SetStatus(func() map[string]interface{} {
+		current := map[string]interface{}{"path": ""}
+		if size, age, ok := bw.CurrentFileStats(); ok {
+			current = map[string]interface{}{
+				"path":        filepath.Base(bw.CurrentFile()),
+				"bytes":       size,
+				"age_seconds": int64(age.Seconds()),
+			}
+		}
+		last := lastInputAt.Load()
+		lastInput := ""
+		if last > 0 {
+			lastInput = time.Unix(0, last).Format(time.RFC3339)
+		}
+		status := map[string]interface{}{
+			"uptime_seconds": int64(time.Since(startTime).Seconds()),
+			"log_level":      strings.ToLower(logLevelVar.Level().String()),
+			"last_input":     lastInput,
+			"lines": map[string]int64{
+				"ingested": metrics.IngestedLines.Value(),
+				"invalid":  metrics.InvalidLines.Value(),
+				"dropped":  metrics.DroppedLines.Value(),
+				"filtered": metrics.FilteredLines.Value(),
+				"written":  metrics.WrittenLines.Value(),
+			},
+			"current_file": current,
+			"upload":       up.Status(100),
+			"last_errors":  logRing.Recent(),
+			"config":       currentConfig.Load().Redacted(),
+			"pipelines":    pipelineStatus(pipes),
+		}
+		if st := flowProc.status(); st != nil {
+			status["flow"] = st
+		}
+		if fetcher != nil {
+			status["remote_config"] = fetcher.Status()
+		}
+		return status
+	})
+
+	srv.HandleAction("rotate", func(r *http.Request) (interface{}, error) {
+		return nil, pipes.rotate()
+	})
+	srv.HandleAction("upload", func(r *http.Request) (interface{}, error) {
+		pipes.triggerScan()
+		return map[string]string{"status": "scheduled"}, nil
+	})
+	srv.HandleAction("diag", func(r *http.Request) (interface{}, error) {
+		if diagCollector == nil {
+			return nil, fmt.Errorf("%w: 诊断采集未启用", admin.ErrUnavailable)
+		}
+		diagCollector.Trigger()
+		return map[string]string{"status": "scheduled"}, nil
+	})
+	srv.HandleAction("log-level", func(r *http.Request) (interface{}, error) {
+		level := r.URL.Query().Get("level")
+		if level == "" {
+			level = r.FormValue("level")
+		}
+		lvl, err := setLogLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", admin.ErrBadRequest, err)
+		}
+		return map[string]string{"log_level": strings.ToLower(lvl.String())}, nil
+	})
+	srv.Handle("/metrics", metrics.Handler())
+
+	go func() {
+		if err := srv.Run(ctx); err != nil {
+			slog.Error("管理接口监听失败", "addr", cfg.Admin.Listen, "err", err)
+		}
+	}()
+}
+
+// pipelineStatus 各管道的输入、当前文件与待上传积压
+func pipelineStatus(pipes pipelineSet) map[string]interface{} {
+	lines := metrics.PipelineLines.Snapshot()
+	out := make(map[string]interface{}, len(pipes))
+	for _, p := range pipes {
+		name := p.cfg.Name
+		current := ""
+		if f := p.bw.CurrentFile(); f != "" {
+			current = filepath.Base(f)
+		}
+		files, size := p.up.Backlog()
+		st := map[string]interface{}{
+			"input":         p.cfg.Input,
+			"data_dir":      p.dataDir,
+			"current_file":  current,
+			"backlog_files": files,
+			"backlog_bytes": size,
+			"lines": map[string]int64{
+				"ingested": lines[name+"|"+metrics.StageIngested],
+				"invalid":  lines[name+"|"+metrics.StageInvalid],
+				"dropped":  lines[name+"|"+metrics.StageDropped],
+				"filtered": lines[name+"|"+metrics.StageFiltered],
+				"written":  lines[name+"|"+metrics.StageWritten],
+			},
+		}
+		if p.proj != nil {
+			st["columns"] = p.proj.Columns()
+		}
+		out[name] = st
+	}
+	return out
+}
+
+// setLogLevel 运行时调整日志级别
+func setLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return 0, errors.New("日志级别仅支持 debug|info|warn|error")
+	}
+	lvl := parseLogLevel(level)
+	logLevelVar.Set(lvl)
+	slog.Info("日志级别已调整", "level", lvl.String())
+	return lvl, nil
+}
+
+// checkDirWritable 通过创建并删除临时文件检查目录可写
+func checkDirWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pmacct/processor/internal/config"
+)
+
+// runCheckConfig 实现 processor check-config：完整检查 pmacct.conf 并打印生效配置
+// 返回进程退出码：0 无问题，1 发现问题，2 参数错误
+func runCheckConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", os.Getenv("PROCESSOR_CONFIG"), "配置文件路径（pmacct.conf）")
+	quiet := fs.Bool("quiet", false, "只输出问题，不打印生效配置")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(stderr, "-config 参数是必需的")
+		return 2
+	}
+
+	res, err := config.CheckFile(*path)
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return 1
+	}
+
+	if res.LoadErr != nil {
+		fmt.Fprintln(stdout, "错误: processor 配置无效:", res.LoadErr)
+	}
+	for _, u := range res.UnknownKeys {
+		if u.Suggestion != "" {
+			fmt.Fprintf(stdout, "错误: 未知配置项 %s（是否为 %s？）\n", u.Key, u.Suggestion)
+		} else {
+			fmt.Fprintf(stdout, "错误: 未知配置项 %s\n", u.Key)
+		}
+	}
+	for _, p := range res.Problems {
+		fmt.Fprintln(stdout, "错误:", p)
+	}
+
+	if res.Config != nil && !*quiet {
+		fmt.Fprintln(stdout, "# 生效配置（含默认值，敏感项已脱敏）")
+		eff := res.Config.Effective()
+		keys := make([]string, 0, len(eff))
+		for k := range eff {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(stdout, "%s: %s\n", k, eff[k])
+		}
+		if len(res.Config.ShadowedKeys) > 0 {
+			fmt.Fprintf(stdout, "# pmacct.conf 覆盖了结构化配置文件中的: %v\n", res.Config.ShadowedKeys)
+		}
+		if len(res.Config.EnvOverrides) > 0 {
+			fmt.Fprintf(stdout, "# 环境变量覆盖: %v\n", res.Config.EnvOverrides)
+		}
+	}
+
+	if !res.OK() {
+		fmt.Fprintf(stdout, "检查未通过：%d 个问题\n", countProblems(res))
+		return 1
+	}
+	fmt.Fprintln(stdout, "检查通过")
+	return 0
+}
+
+func countProblems(res *config.CheckResult) int {
+	n := len(res.UnknownKeys) + len(res.Problems)
+	if res.LoadErr != nil {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pmacct/processor/internal/diag"
+	"github.com/pmacct/processor/internal/statusreport"
+)
+
+// registerCommands 注册可由状态上报响应下发的指令，均作用于所有管道（回执中列出管道名）
+func registerCommands(reporter *statusreport.Reporter, pipes pipelineSet, diagCollector *diag.Collector) {
+	reporter.HandleCommand("rotate_interval_sec", func(value string) (string, error) {
+		sec, err := parseIntervalValue(value)
+		if err != nil {
+			return "", err
+		}
+		for _, p := range pipes {
+			p.bw.SetRotateInterval(sec)
+		}
+		return fmt.Sprintf("rotate_interval_sec=%d pipelines=%s", sec, pipes.names()), nil
+	})
+	reporter.HandleCommand("upload_interval_sec", func(value string) (string, error) {
+		sec, err := parseIntervalValue(value)
+		if err != nil {
+			return "", err
+		}
+		for _, p := range pipes {
+			p.up.SetUploadInterval(sec)
+		}
+		return fmt.Sprintf("upload_interval_sec=%d pipelines=%s", sec, pipes.names()), nil
+	})
+	reporter.HandleCommand("rotate", func(string) (string, error) {
+		return "rotated", pipes.rotate()
+	})
+	reporter.HandleCommand("upload", func(string) (string, error) {
+		pipes.triggerScan()
+		return "scheduled", nil
+	})
+	reporter.HandleCommand("diag", func(string) (string, error) {
+		if diagCollector == nil {
+			return "", errors.New("诊断采集未启用")
+		}
+		diagCollector.Trigger()
+		return "scheduled", nil
+	})
+	reporter.HandleCommand("log_level", func(value string) (string, error) {
+		lvl, err := setLogLevel(value)
+		if err != nil {
+			return "", err
+		}
+		return "log_level=" + strings.ToLower(lvl.String()), nil
+	})
+}
+
+// parseIntervalValue 解析指令中的间隔秒数（1 秒 ~ 1 天）
+func parseIntervalValue(value string) (int, error) {
+	sec, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("间隔不是整数: %q", value)
+	}
+	if sec < 1 || sec > 86400 {
+		return 0, fmt.Errorf("间隔需在 1~86400 秒之间: %d", sec)
+	}
+	return sec, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pmacct/processor/internal/anonymize"
+	"github.com/pmacct/processor/internal/classify"
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/enrich"
+	"github.com/pmacct/processor/internal/filter"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/model"
+	"github.com/pmacct/processor/internal/projection"
+)
+
+// flowProcessor 校验通过后、写入前对每条流的处理：采样率校正 -> 过滤 -> 富化（追加列）-> 匿名化
+// 过滤与富化基于真实地址，匿名化在最后一步进行，所有管道共用
+type flowProcessor struct {
+	sampling config.SamplingConfig
+	filters  atomic.Pointer[filter.Filter] // 未启用时为 nil；SIGHUP 时整体替换
+	enrich   enrich.Chain
+	anon     *anonymize.Anonymizer // 未启用时为 nil
+	services *classify.Classifier  // 服务识别（计数始终进行，列按配置追加）
+}
+
+// newFlowProcessor 按配置构建各处理步骤，后台任务（如 GeoIP 库重新加载）随 ctx 结束
+func newFlowProcessor(ctx context.Context, cfg *config.ProcessorConfig) (*flowProcessor, error) {
+	fp := &flowProcessor{sampling: cfg.Sampling}
+	fl, err := buildFilter(cfg.Filter)
+	if err != nil {
+		return nil, err
+	}
+	fp.filters.Store(fl)
+	if cfg.GeoIP.Enabled() {
+		geoip, err := enrich.NewGeoIP(cfg.GeoIP)
+		if err != nil {
+			return nil, fmt.Errorf("初始化 GeoIP 富化失败: %w", err)
+		}
+		go geoip.Run(ctx.Done())
+		fp.enrich = append(fp.enrich, geoip)
+	}
+	if cfg.Zoning.Enabled() {
+		fp.enrich = append(fp.enrich, enrich.NewZones(cfg.Zoning))
+	}
+	if fp.services, err = classify.New(cfg.Services.File, cfg.Services.Overrides); err != nil {
+		return nil, fmt.Errorf("加载服务表失败: %w", err)
+	}
+	if cfg.Services.Columns {
+		fp.enrich = append(fp.enrich, enrich.NewServices(fp.services))
+	}
+	if cfg.Anonymize.Enabled() {
+		anon, err := anonymize.New(cfg.Anonymize)
+		if err != nil {
+			return nil, fmt.Errorf("初始化地址匿名化失败: %w", err)
+		}
+		fp.anon = anon
+	}
+	return fp, nil
+}
+
+// buildFilter 编译过滤规则（未配置规则时返回 nil）
+func buildFilter(cfg config.FilterConfig) (*filter.Filter, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	rules := make([]*filter.Rule, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rule, err := filter.Compile(r.Name, r.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("编译过滤规则 %s 失败: %w", r.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return filter.New(cfg.Mode, rules)
+}
+
+// enabled 是否需要逐条解析处理（否则原样写入）
+func (fp *flowProcessor) enabled() bool {
+	return fp.sampling.Enabled() || fp.filters.Load() != nil || len(fp.enrich) > 0 || fp.anon != nil
+}
+
+// process 处理一条已校验的数据行并返回处理后的流；rate 为该行 SAMPLING_RATE 列的取值（无该列时为 0）。
+// keep 为 false 表示该流被过滤器丢弃
+func (fp *flowProcessor) process(line string, rate int) (flow *model.Flow, keep bool, err error) {
+	flow, err = model.ParseFlow(line)
+	if err != nil {
+		return nil, false, err
+	}
+	if fp.sampling.Enabled() {
+		r := fp.samplingRate(rate)
+		if flow.Scale(uint64(r)) {
+			metrics.SamplingAnomalies.Inc(metrics.SamplingSaturated)
+		}
+		flow.Extra = append(flow.Extra, strconv.Itoa(r))
+	}
+	if fl := fp.filters.Load(); fl != nil && !fl.Keep(flow) {
+		return nil, false, nil
+	}
+	fp.enrich.Apply(flow)
+	if fp.anon != nil {
+		fp.anon.Apply(flow)
+	}
+	return flow, true, nil
+}
+
+// countService 按服务统计一条保留的数据行（前 11 列，不依赖逐条处理是否启用）
+func (fp *flowProcessor) countService(line string) {
+	fields := strings.SplitN(line, ",", model.ColProto+2)
+	if len(fields) <= model.ColProto {
+		return
+	}
+	srcPort, _ := strconv.ParseUint(strings.TrimSpace(fields[model.ColSrcPort]), 10, 16)
+	dstPort, _ := strconv.ParseUint(strings.TrimSpace(fields[model.ColDstPort]), 10, 16)
+	proto, err := strconv.ParseUint(strings.TrimSpace(fields[model.ColProto]), 10, 8)
+	if err != nil {
+		return
+	}
+	svc := fp.services.Classify(uint8(proto), uint16(srcPort), uint16(dstPort))
+	metrics.FlowsByService.Inc(svc.Name)
+}
+
+// samplingRate 本条流采用的采样率：按 exporter 校正且该行带有效采样率（1~MaxSamplingRate）时使用该值，否则使用配置的采样率
+func (fp *flowProcessor) samplingRate(rate int) int {
+	if fp.sampling.PerExporter && rate > config.MaxSamplingRate {
+		metrics.SamplingAnomalies.Inc(metrics.SamplingRateOutOfRange)
+		return fp.sampling.Rate
+	}
+	if fp.sampling.PerExporter && rate > 0 {
+		return rate
+	}
+	return fp.sampling.Rate
+}
+
+// redact 匿名化启用时不在日志与错误行文件中保留原始行
+func (fp *flowProcessor) redact(line string) string {
+	if fp.anon != nil {
+		return "(已匿名化，原始行不保留)"
+	}
+	return line
+}
+
+// columns 处理后的全部列名（未配置输出列时即输出文件的列）
+func (fp *flowProcessor) columns() []string {
+	cols := append([]string{}, model.BaseColumns...)
+	if fp.sampling.Enabled() {
+		cols = append(cols, "sampling_rate")
+	}
+	return append(cols, fp.enrich.Columns()...)
+}
+
+// manifest 管道数据文件附带的 manifest 固定内容（未启用任何处理且未配置输出列时为 nil，不写 manifest）
+func (fp *flowProcessor) manifest(pc config.PipelineConfig, proj *projection.Projection) map[string]interface{} {
+	if !fp.enabled() && proj == nil {
+		return nil
+	}
+	columns := fp.columns()
+	if proj != nil {
+		columns = proj.Columns()
+	}
+	anon := map[string]interface{}{"mode": config.AnonymizeNone}
+	if fp.anon != nil {
+		anon = fp.anon.Policy()
+	}
+	return map[string]interface{}{
+		"pipeline":      pc.Name,
+		"schema":        pc.Schema,
+		"columns":       columns,
+		"anonymization": anon,
+		"sampling":      fp.samplingPolicy(),
+	}
+}
+
+// samplingPolicy 采样率校正策略（写入 manifest 与 /status）
+func (fp *flowProcessor) samplingPolicy() map[string]interface{} {
+	return map[string]interface{}{
+		"rate":         fp.sampling.Rate,
+		"source":       fp.sampling.Source,
+		"per_exporter": fp.sampling.PerExporter,
+		"scaled":       fp.sampling.Enabled(),
+	}
+}
+
+// status /status 中的 flow 段（未启用任何处理时为 nil）
+func (fp *flowProcessor) status() map[string]interface{} {
+	if !fp.enabled() {
+		return nil
+	}
+	out := map[string]interface{}{"columns": fp.columns(), "sampling": fp.samplingPolicy()}
+	if fl := fp.filters.Load(); fl != nil {
+		out["filter"] = fl.Status()
+	}
+	for _, s := range fp.enrich {
+		if st, ok := s.(interface{ Status() map[string]interface{} }); ok {
+			out[s.Name()] = st.Status()
+		}
+	}
+	if fp.anon != nil {
+		out["anonymization"] = fp.anon.Policy()
+	}
+	return out
+}
@@ -5,25 +5,31 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
-	"syscall"
 	"sync/atomic"
+	"syscall"
 	"time"
+	// 内置时区数据，processor_timezone 不依赖系统 tzdata
+	_ "time/tzdata"
 
-	"github.com/pmacct/processor/internal/batchwriter"
 	"github.com/pmacct/processor/internal/config"
 	"github.com/pmacct/processor/internal/diag"
-	"github.com/pmacct/processor/internal/errorlog"
+	"github.com/pmacct/processor/internal/metrics"
 	"github.com/pmacct/processor/internal/model"
+	"github.com/pmacct/processor/internal/otlp"
+	"github.com/pmacct/processor/internal/remoteconfig"
 	"github.com/pmacct/processor/internal/statusreport"
-	"github.com/pmacct/processor/internal/uploader"
 	"github.com/pmacct/processor/internal/validator"
 )
 
+// version 构建版本，通过 -ldflags "-X main.version=..." 注入
+var version = "dev"
+
 var (
 	configPath = flag.String("config", "", "配置文件路径（pmacct.conf，含 processor_* 配置）")
 	dataDir    = flag.String("data-dir", "", "本地缓存目录，存放滚动生成的压缩文件")
@@ -31,6 +37,9 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		os.Exit(runCheckConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	flag.Parse()
 	setupLogger(*logLevel)
 
@@ -50,13 +59,50 @@ func main() {
 		slog.Error("加载配置失败", "err", err)
 		os.Exit(1)
 	}
+
+	// 远程配置：先叠加本地缓存的上次生效版本，启动后再异步拉取最新版本
+	var fetcher *remoteconfig.Fetcher
+	var overlay *config.Overlay
+	if cfg.RemoteConfig.URL != "" {
+		fetcher, err = remoteconfig.NewFetcher(cfg.RemoteConfig, *dataDir)
+		if err != nil {
+			slog.Error("初始化远程配置拉取失败", "err", err)
+			os.Exit(1)
+		}
+		if cached := fetcher.Cached(); cached != nil {
+			if rcfg, err := config.LoadConfigWithOverlay(*configPath, cached); err != nil {
+				slog.Warn("缓存的远程配置无效，使用本地配置", "err", err)
+			} else {
+				cfg, overlay = rcfg, cached
+			}
+		}
+	}
+	if cfg.LogLevel != "" {
+		logLevelVar.Set(parseLogLevel(cfg.LogLevel))
+	}
+	currentConfig.Store(cfg)
+	targetNames := make([]string, 0, len(cfg.Upload.Targets))
+	for _, t := range cfg.Upload.Targets {
+		targetNames = append(targetNames, fmt.Sprintf("%s(%s:%d)", t.Name, t.Host, t.Port))
+	}
 	slog.Info("配置加载成功",
-		"ftp_host", cfg.FTPHost,
-		"ftp_port", cfg.FTPPort,
+		"upload_targets", strings.Join(targetNames, ","),
+		"upload_policy", cfg.Upload.Policy,
 		"rotate_interval_sec", cfg.RotateIntervalSec,
 		"rotate_size_mb", cfg.RotateSizeMB,
 		"upload_interval_sec", cfg.UploadIntervalSec,
+		"env_overrides", strings.Join(cfg.EnvOverrides, ","),
+		"resolved_refs", strings.Join(cfg.ResolvedRefs, ","),
+		"structured_file", cfg.StructuredFile,
+		"remote_keys", strings.Join(cfg.RemoteKeys, ","),
 	)
+	if len(cfg.ShadowedKeys) > 0 {
+		slog.Warn("结构化配置文件中的以下配置项被 pmacct.conf 同名配置覆盖", "keys", strings.Join(cfg.ShadowedKeys, ","))
+	}
+	slog.Debug("生效配置", "values", cfg.Redacted())
+	for _, u := range config.UnknownKeys(cfg.Values) {
+		slog.Warn("未知配置项，已忽略（可用 processor check-config 检查）", "key", u.Key, "suggestion", u.Suggestion)
+	}
 
 	// 确保数据目录存在
 	if err := config.EnsureDataDir(*dataDir); err != nil {
@@ -65,91 +111,173 @@ func main() {
 	}
 	slog.Info("数据目录已就绪", "data_dir", *dataDir)
 
-	// 错误行落盘
-	errWriter, err := errorlog.NewLineWriter(*dataDir)
-	if err != nil {
-		slog.Error("初始化错误行写入器失败", "err", err)
-	}
-
 	// 公共上下文
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 创建批处理 Writer
-	bw := batchwriter.NewBatchWriter(*dataDir, cfg.FilePrefix, cfg.RotateIntervalSec, cfg.RotateSizeMB)
+	// 流处理：校验通过的流依次富化、匿名化后再写入，所有管道共用
+	flowProc, err := newFlowProcessor(ctx, cfg)
+	if err != nil {
+		slog.Error("初始化流处理失败", "err", err)
+		os.Exit(1)
+	}
+	if flowProc.enabled() {
+		slog.Info("已启用流处理，数据文件附带 manifest", "columns", strings.Join(flowProc.columns(), ","), "anonymize", cfg.Anonymize.Mode)
+	}
+
+	// 创建处理管道：默认管道读取 stdin，命名管道各自打开输入，每条管道独立写入与上传
+	pipes := make(pipelineSet, 0, 1+len(cfg.Pipelines))
+	for _, pc := range append([]config.PipelineConfig{cfg.DefaultPipeline()}, cfg.Pipelines...) {
+		p, err := newPipeline(ctx, pc, pipelineDataDir(*dataDir, pc.Name), cfg.IngestChanCapacity, flowProc)
+		if err != nil {
+			slog.Error("初始化管道失败", "pipeline", pc.Name, "err", err)
+			os.Exit(1)
+		}
+		pipes = append(pipes, p)
+	}
+	bw := pipes[0].bw
 
 	// 状态上报器
-	reporter, err := statusreport.NewReporter(cfg.StatusReport)
+	reporter, err := statusreport.NewReporter(cfg.StatusReport, *dataDir)
 	if err != nil {
 		slog.Error("初始化状态上报失败", "err", err)
 		os.Exit(1)
 	}
-	// 运行上报 goroutine（如果启用）
-	if reporter != nil {
-		go reporter.Run(ctx.Done())
-		slog.Info("状态上报已启用", "url", cfg.StatusReport.URL, "interval_sec", cfg.StatusReport.IntervalSec)
-	}
-
-	// 创建 Uploader
-	up := uploader.NewUploader(
-		ctx,
-		cfg.FTPHost,
-		cfg.FTPPort,
-		cfg.FTPUser,
-		cfg.FTPPass,
-		cfg.FTPDir,
-		cfg.FTPOptions.TimeoutSec,
-		*dataDir,
-		cfg.UploadIntervalSec,
-	)
 
 	// 启动上传器
-	up.Start()
-	slog.Info("FTP 上传器已启动", "interval_sec", cfg.UploadIntervalSec)
+	for _, p := range pipes {
+		p.up.Start()
+		slog.Info("FTP 上传器已启动", "pipeline", p.cfg.Name, "interval_sec", p.cfg.UploadIntervalSec, "targets", len(p.cfg.Upload.Targets), "policy", p.cfg.Upload.Policy)
+	}
+
+	// OTLP 导出（指标 + 文件生命周期 trace）
+	exporter := otlp.NewExporter(cfg.OTLP, version, cfg.Upload.InstanceID)
+	if exporter != nil {
+		exporter.Start()
+		slog.Info("OTLP 导出已启用", "endpoint", cfg.OTLP.Endpoint, "metrics", cfg.OTLP.MetricsEnabled, "traces", cfg.OTLP.TracesEnabled)
+	}
 
 	// 启动诊断采集（宿主机日志结构化 + 进程日志）
 	var diagCollector *diag.Collector
 	var csvTotal atomic.Int64
-	var csvDNS atomic.Int64
 	if cfg.Diag.Enabled {
 		diagCollector = diag.NewCollector(ctx, cfg.Diag, *dataDir)
 		diagCollector.SetProcCSVStats(func(procName string) (int64, int64) {
 			if procName != "processor" {
 				return 0, 0
 			}
-			return csvTotal.Load(), csvDNS.Load()
+			return csvTotal.Load(), serviceFlows("dns")
 		})
 		diagCollector.Start()
 		slog.Info("诊断采集已启用", "interval_sec", cfg.Diag.IntervalSec)
 	}
 
-	// 创建数据通道（带缓冲）
-	dataChan := make(chan model.DataLine, cfg.IngestChanCapacity)
+	// 运行上报 goroutine（如果启用）
+	if reporter != nil {
+		reporter.SetHealthFunc(func() statusreport.Health {
+			backlogFiles, backlogBytes := pipes.backlog()
+			return statusreport.Health{
+				Version:    version,
+				ConfigHash: currentConfig.Load().ConfigHash,
+				Lines: statusreport.LineStats{
+					Ingested: metrics.IngestedLines.Value(),
+					Invalid:  metrics.InvalidLines.Value(),
+					Dropped:  metrics.DroppedLines.Value(),
+					Written:  metrics.WrittenLines.Value(),
+				},
+				Upload: statusreport.UploadStats{
+					BacklogFiles: backlogFiles,
+					BacklogBytes: backlogBytes,
+					LastSuccess:  pipes.lastSuccess(),
+				},
+				Protocols: metrics.FlowsByProto.Snapshot(),
+				Services:  metrics.FlowsByService.Snapshot(),
+				DNS:       statusreport.DNSStats{Flows: serviceFlows("dns"), Total: csvTotal.Load()},
+			}
+		})
+		registerCommands(reporter, pipes, diagCollector)
+		go reporter.Run(ctx.Done())
+		slog.Info("状态上报已启用", "url", cfg.StatusReport.URL, "interval_sec", cfg.StatusReport.IntervalSec, "schema_version", cfg.StatusReport.SchemaVersion, "commands", cfg.StatusReport.CommandsEnabled)
+	}
+
+	// 管理接口（健康检查、状态查询、运行时动作）
+	startAdmin(ctx, cfg, *dataDir, pipes, diagCollector, fetcher, flowProc)
+
+	// Prometheus 指标（通道与积压为所有管道之和，当前文件为默认管道）
+	metrics.ChannelDepth.SetFunc(func() float64 {
+		depth, _ := pipes.channelUsage()
+		return float64(depth)
+	})
+	metrics.ChannelCapacity.SetFunc(func() float64 {
+		_, capacity := pipes.channelUsage()
+		return float64(capacity)
+	})
+	metrics.CurrentFileBytes.SetFunc(func() float64 {
+		size, _, _ := bw.CurrentFileStats()
+		return float64(size)
+	})
+	metrics.CurrentFileAge.SetFunc(func() float64 {
+		_, age, _ := bw.CurrentFileStats()
+		return age.Seconds()
+	})
+	metrics.BacklogFiles.SetFunc(func() float64 {
+		count, _ := pipes.backlog()
+		return float64(count)
+	})
+	metrics.BacklogBytes.SetFunc(func() float64 {
+		_, size := pipes.backlog()
+		return float64(size)
+	})
+	if cfg.MetricsListen != "" {
+		go func() {
+			if err := metrics.Serve(ctx, cfg.MetricsListen); err != nil {
+				slog.Error("metrics 监听失败", "addr", cfg.MetricsListen, "err", err)
+			}
+		}()
+	}
 
 	// 启动 writer goroutine
-	writerDone := make(chan error, 1)
-	go func() {
-		writerDone <- runBatchWriter(ctx, bw, dataChan)
-	}()
+	for _, p := range pipes {
+		go func(p *pipeline) {
+			p.writerDone <- runBatchWriter(ctx, p)
+		}(p)
+	}
 
 	// 设置信号处理
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// 启动从 stdin 读取的 goroutine
-	ingestDone := make(chan error, 1)
-	go func() {
-		ingestDone <- runIngest(
-			ctx,
-			dataChan,
-			reporter,
-			cfg.DebugPrintInterval,
-			time.Duration(cfg.IngestChanTimeoutMs)*time.Millisecond,
-			errWriter,
-			&csvTotal,
-			&csvDNS,
-		)
-	}()
+	// SIGHUP 热加载配置
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	rl := &reloader{configPath: *configPath, pipes: pipes, reporter: reporter, diag: diagCollector, flow: flowProc}
+	rl.overlay.Store(overlay)
+	go rl.run(hupChan, ctx.Done())
+	if fetcher != nil {
+		go fetcher.Run(ctx.Done(), rl.applyRemote)
+		slog.Info("远程配置拉取已启用", "url", cfg.RemoteConfig.URL, "interval_sec", cfg.RemoteConfig.IntervalSec)
+	}
+
+	// 启动读取输入的 goroutine：默认管道读取 stdin，命名管道读取各自的 FIFO/套接字
+	chanTimeout := time.Duration(cfg.IngestChanTimeoutMs) * time.Millisecond
+	for _, p := range pipes {
+		go func(p *pipeline) {
+			ingest := func(r io.Reader) error {
+				return runIngest(ctx, r, p, reporter, cfg.DebugPrintInterval, chanTimeout, &csvTotal)
+			}
+			if p.input == nil {
+				p.ingestDone <- ingest(os.Stdin)
+				return
+			}
+			slog.Info("管道输入已就绪", "pipeline", p.cfg.Name, "input", p.cfg.Input, "data_dir", p.dataDir)
+			err := p.input.serve(ctx, ingest)
+			if err != nil {
+				slog.Error("管道输入异常结束", "pipeline", p.cfg.Name, "input", p.cfg.Input, "err", err)
+			}
+			p.ingestDone <- err
+		}(p)
+	}
+	primary := pipes[0]
 
 	// 等待信号或完成
 	select {
@@ -157,47 +285,52 @@ func main() {
 		slog.Info("收到信号，开始优雅关闭", "signal", sig.String())
 		cancel()
 		// 等待所有 goroutine 完成
-		<-ingestDone
-		close(dataChan)
-		<-writerDone
-	case err := <-ingestDone:
+		<-primary.ingestDone
+		close(primary.dataChan)
+		<-primary.writerDone
+	case err := <-primary.ingestDone:
 		if err != nil {
 			slog.Error("读取 stdin 时出错", "err", err)
 		}
-		close(dataChan)
-		<-writerDone
-	case err := <-writerDone:
+		close(primary.dataChan)
+		<-primary.writerDone
+	case err := <-primary.writerDone:
 		if err != nil {
 			slog.Error("批量写入时出错", "err", err)
 		}
 		cancel()
-		<-ingestDone
+		<-primary.ingestDone
 	}
 
-	// 关闭 batch writer（确保当前文件被正确关闭和重命名）
-	if err := bw.Close(); err != nil {
-		slog.Error("关闭 batch writer 失败", "err", err)
-	} else {
-		slog.Info("Batch writer 已关闭")
+	// 默认管道结束后停止命名管道
+	cancel()
+	for _, p := range pipes[1:] {
+		<-p.ingestDone
+		close(p.dataChan)
+		<-p.writerDone
 	}
 
-	if errWriter != nil {
-		if err := errWriter.Close(); err != nil {
-			slog.Error("关闭错误行写入器失败", "err", err)
-		}
+	// 关闭 batch writer（确保当前文件被正确关闭和重命名）
+	for _, p := range pipes {
+		p.close()
 	}
 
 	if diagCollector != nil {
 		diagCollector.Stop()
 	}
 	// 停止上传器
-	up.Stop()
+	for _, p := range pipes {
+		p.up.Stop()
+	}
+	if exporter != nil {
+		exporter.Stop()
+	}
 	slog.Info("程序退出")
 }
 
-// parseCounts 从行中按索引解析包/字节数
+// parseCounts 从 CSV 行中按索引解析包/字节数（状态上报的 traffic 统计）
 func parseCounts(line string, pktIdx, byteIdx int) (int64, int64) {
-	fields := strings.Split(line, "|")
+	fields := strings.Split(line, ",")
 	if pktIdx >= len(fields) || byteIdx >= len(fields) {
 		return 0, 0
 	}
@@ -206,6 +339,17 @@ func parseCounts(line string, pktIdx, byteIdx int) (int64, int64) {
 	return pkts, bytes
 }
 
+// splitSamplingRate 移除第 idx 列（SAMPLING_RATE）并返回其取值，列不存在或取值无效时返回 0
+func splitSamplingRate(line string, idx int) (string, int) {
+	fields := strings.Split(line, ",")
+	if idx >= len(fields) {
+		return line, 0
+	}
+	rate, _ := strconv.Atoi(strings.TrimSpace(fields[idx]))
+	fields = append(fields[:idx], fields[idx+1:]...)
+	return strings.Join(fields, ","), rate
+}
+
 // isHeaderLine 判断是否为表头行（用于丢弃表头）
 func isHeaderLine(line string) bool {
 	lower := strings.ToLower(line)
@@ -224,14 +368,27 @@ func isHeaderLine(line string) bool {
 	return false
 }
 
-func isDNSLine(line string) bool {
+// serviceFlows 返回某一服务的累计流数（用于诊断与状态上报中的 DNS 统计）
+func serviceFlows(service string) int64 {
+	return metrics.FlowsByService.Snapshot()[service]
+}
+
+// recordFlowMetrics 统计已校验行的协议分布与包/字节数（输出行的追加列不参与）
+// 列顺序：SRC_IP,DST_IP,SRC_PORT,DST_PORT,TCP_FLAGS,PROTOCOL,TOS,TIMESTAMP_MIN,TIMESTAMP_MAX,PACKETS,BYTES
+func recordFlowMetrics(line string) {
 	fields := strings.Split(line, ",")
-	if len(fields) < 4 {
-		return false
+	if len(fields) < 11 {
+		return
+	}
+	if proto, err := strconv.Atoi(strings.TrimSpace(fields[5])); err == nil {
+		metrics.FlowsByProto.Inc(metrics.ProtoName(proto))
+	}
+	if pkts, err := strconv.ParseInt(strings.TrimSpace(fields[9]), 10, 64); err == nil {
+		metrics.FlowPackets.Add(pkts)
+	}
+	if bytes, err := strconv.ParseInt(strings.TrimSpace(fields[10]), 10, 64); err == nil {
+		metrics.FlowBytes.Add(bytes)
 	}
-	srcPort := strings.TrimSpace(fields[2])
-	dstPort := strings.TrimSpace(fields[3])
-	return srcPort == "53" || dstPort == "53"
 }
 
 // min 返回两个整数中的较小值
@@ -242,13 +399,18 @@ func min(a, b int) int {
 	return b
 }
 
-// runIngest 从标准输入读取数据并放入channel
-func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *statusreport.Reporter, debugPrintInterval int, chanTimeout time.Duration, errWriter *errorlog.LineWriter, csvTotal *atomic.Int64, csvDNS *atomic.Int64) error {
-	scanner := bufio.NewScanner(os.Stdin)
+// runIngest 从管道输入读取数据并放入该管道的 channel
+func runIngest(ctx context.Context, in io.Reader, p *pipeline, reporter *statusreport.Reporter, debugPrintInterval int, chanTimeout time.Duration, csvTotal *atomic.Int64) error {
+	scanner := bufio.NewScanner(in)
+	dataChan, errWriter := p.dataChan, p.errWriter
+	name := p.cfg.Name
 	lineCount := 0
 	headerProcessed := false
-	packetIdx := -1
-	octetIdx := -1
+	// 默认按 11 列 CSV 的 PACKETS/BYTES 列统计，表头存在时以表头为准（列名 packets/bytes 或 packetTotalCount/octetTotalCount）
+	packetIdx := 9
+	octetIdx := 10
+	// 表头含 SAMPLING_RATE 列（aggregate 含 sampling_rate）时的列下标，该列在校验前移除
+	samplingIdx := -1
 
 	for {
 		select {
@@ -257,11 +419,15 @@ func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *st
 		default:
 			if !scanner.Scan() {
 				if err := scanner.Err(); err != nil {
-					slog.Error("读取 stdin 失败", "err", err)
-					return fmt.Errorf("读取 stdin 失败: %w", err)
+					if ctx.Err() != nil {
+						// 关闭时输入被主动关闭
+						return ctx.Err()
+					}
+					slog.Error("读取输入失败", "pipeline", name, "input", p.cfg.Input, "err", err)
+					return fmt.Errorf("读取 %s 失败: %w", p.cfg.Input, err)
 				}
 				// EOF
-				slog.Info("从 stdin 读取完成", "lines", lineCount)
+				slog.Info("输入读取完成", "pipeline", name, "input", p.cfg.Input, "lines", lineCount)
 				return nil
 			}
 
@@ -269,6 +435,7 @@ func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *st
 			if len(line) == 0 {
 				continue
 			}
+			lastInputAt.Store(time.Now().UnixNano())
 
 			currentLineNo := lineCount + 1
 
@@ -278,14 +445,23 @@ func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *st
 				if isHeaderLine(line) {
 					headerProcessed = true
 
-					// 解析字段索引（包/字节统计）
-					fields := strings.Split(line, "|")
+					// 解析字段索引（包/字节统计），下标按移除 SAMPLING_RATE 列之后计算
+					fields := strings.Split(line, ",")
 					for i, f := range fields {
-						ft := strings.TrimSpace(f)
-						switch ft {
-						case "packetTotalCount":
+						if strings.EqualFold(strings.TrimSpace(f), "sampling_rate") {
+							samplingIdx = i
+							fields = append(fields[:i:i], fields[i+1:]...)
+							break
+						}
+					}
+					if samplingIdx >= 0 && !p.flow.sampling.PerExporter {
+						slog.Warn("输入含 SAMPLING_RATE 列，未开启 processor_sampling_per_exporter，该列将被忽略", "pipeline", name)
+					}
+					for i, f := range fields {
+						switch strings.ToLower(strings.TrimSpace(f)) {
+						case "packets", "packettotalcount":
 							packetIdx = i
-						case "octetTotalCount":
+						case "bytes", "octettotalcount":
 							octetIdx = i
 						}
 					}
@@ -293,10 +469,19 @@ func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *st
 				}
 			}
 
+			metrics.IngestedLines.Inc()
+			metrics.PipelineLines.Inc(name, metrics.StageIngested)
+			rawLine := line
+			rate := 0
+			if samplingIdx >= 0 {
+				line, rate = splitSamplingRate(line, samplingIdx)
+			}
 			if ok, reason := validator.ValidateLine(line, time.Now()); !ok {
-				slog.Warn("无效CSV行", "line_no", currentLineNo, "reason", reason, "line", line)
+				metrics.InvalidLines.Inc()
+				metrics.PipelineLines.Inc(name, metrics.StageInvalid)
+				slog.Warn("无效CSV行", "pipeline", name, "line_no", currentLineNo, "reason", reason, "line", p.flow.redact(rawLine))
 				if errWriter != nil {
-					if err := errWriter.Write(currentLineNo, line, reason); err != nil {
+					if err := errWriter.Write(currentLineNo, p.flow.redact(rawLine), reason); err != nil {
 						slog.Error("写入 errorline.csv 失败", "err", err)
 					}
 				}
@@ -304,19 +489,39 @@ func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *st
 				continue
 			}
 
+			// 处理数据行；countLine 为投影前的行（11 列在前），用于流量指标与上报
+			outputLine, countLine := line, line
+			if p.flow.enabled() || p.proj != nil {
+				flow, keep, err := p.flow.process(line, rate)
+				if err != nil {
+					// 已通过校验，正常不会出现；匿名化启用时不能原样写入，按无效行处理
+					metrics.InvalidLines.Inc()
+					metrics.PipelineLines.Inc(name, metrics.StageInvalid)
+					slog.Warn("解析流记录失败", "pipeline", name, "line_no", currentLineNo, "err", err)
+					lineCount++
+					continue
+				}
+				if !keep {
+					metrics.FilteredLines.Inc()
+					metrics.PipelineLines.Inc(name, metrics.StageFiltered)
+					lineCount++
+					continue
+				}
+				countLine = flow.Line()
+				outputLine = countLine
+				if p.proj != nil {
+					outputLine = p.proj.Line(flow)
+				}
+			}
+
+			// 流量指标、服务计数与上报的包/字节数均只统计过滤后保留的流，并取校正后的值
 			if csvTotal != nil {
 				csvTotal.Add(1)
 			}
-			if csvDNS != nil && isDNSLine(line) {
-				csvDNS.Add(1)
-			}
-
-			// 处理数据行
-			outputLine := line
-
-			// 统计包/字节数
+			p.flow.countService(countLine)
+			recordFlowMetrics(countLine)
 			if reporter != nil && packetIdx >= 0 && octetIdx >= 0 {
-				if pkts, bytes := parseCounts(outputLine, packetIdx, octetIdx); pkts > 0 || bytes > 0 {
+				if pkts, bytes := parseCounts(countLine, packetIdx, octetIdx); pkts > 0 || bytes > 0 {
 					reporter.Add(pkts, bytes)
 				}
 			}
@@ -340,20 +545,23 @@ func runIngest(ctx context.Context, dataChan chan<- model.DataLine, reporter *st
 					return ctx.Err()
 				case <-time.After(chanTimeout):
 					// channel满时，记录警告并丢弃数据
-					slog.Warn("数据通道满，丢弃数据行", "line", outputLine[:min(len(outputLine), 100)])
+					metrics.DroppedLines.Inc()
+					metrics.PipelineLines.Inc(name, metrics.StageDropped)
+					slog.Warn("数据通道满，丢弃数据行", "pipeline", name, "line", outputLine[:min(len(outputLine), 100)])
 				}
 			}
 
 			lineCount++
 			if lineCount%10000 == 0 {
-				slog.Info("已处理行数", "lines", lineCount)
+				slog.Info("已处理行数", "pipeline", name, "lines", lineCount)
 			}
 		}
 	}
 }
 
-// runBatchWriter 从channel批量读取数据并写入文件
-func runBatchWriter(ctx context.Context, bw *batchwriter.BatchWriter, dataChan <-chan model.DataLine) error {
+// runBatchWriter 从管道的 channel 批量读取数据并写入文件
+func runBatchWriter(ctx context.Context, p *pipeline) error {
+	bw, dataChan := p.bw, p.dataChan
 	// 批量处理的缓冲区
 	batch := make([]model.DataLine, 0, 1000)
 	ticker := time.NewTicker(1 * time.Second)
@@ -373,6 +581,8 @@ func runBatchWriter(ctx context.Context, bw *batchwriter.BatchWriter, dataChan <
 				return fmt.Errorf("刷新缓冲区失败: %w", err)
 			}
 			totalLines += int64(len(batch))
+			metrics.WrittenLines.Add(int64(len(batch)))
+			metrics.PipelineLines.Add(int64(len(batch)), p.cfg.Name, metrics.StageWritten)
 			batch = batch[:0] // 清空批次
 		}
 		return nil
@@ -389,7 +599,7 @@ func runBatchWriter(ctx context.Context, bw *batchwriter.BatchWriter, dataChan <
 			if err := flushBatch(); err != nil {
 				return err
 			}
-			slog.Info("批量写入器统计", "processed_lines", totalLines, "dropped_lines", droppedLines)
+			slog.Info("批量写入器统计", "pipeline", p.cfg.Name, "processed_lines", totalLines, "dropped_lines", droppedLines)
 			return nil
 		case dataLine, ok := <-dataChan:
 			if !ok {
@@ -397,7 +607,7 @@ func runBatchWriter(ctx context.Context, bw *batchwriter.BatchWriter, dataChan <
 				if err := flushBatch(); err != nil {
 					return err
 				}
-				slog.Info("批量写入器统计", "processed_lines", totalLines, "dropped_lines", droppedLines)
+				slog.Info("批量写入器统计", "pipeline", p.cfg.Name, "processed_lines", totalLines, "dropped_lines", droppedLines)
 				return nil
 			}
 
@@ -417,15 +627,15 @@ func runBatchWriter(ctx context.Context, bw *batchwriter.BatchWriter, dataChan <
 			}
 		case <-reportTicker.C:
 			// 定期报告处理统计信息
-			slog.Info("批量写入器统计", "processed_lines", totalLines, "dropped_lines", droppedLines)
+			slog.Info("批量写入器统计", "pipeline", p.cfg.Name, "processed_lines", totalLines, "dropped_lines", droppedLines)
 		}
 	}
 }
 
 func setupLogger(level string) {
-	lvl := parseLogLevel(level)
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl})
-	slog.SetDefault(slog.New(handler))
+	logLevelVar.Set(parseLogLevel(level))
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevelVar})
+	slog.SetDefault(slog.New(logRing.Wrap(handler)))
 }
 
 func parseLogLevel(s string) slog.Level {
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pmacct/processor/internal/batchwriter"
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/errorlog"
+	"github.com/pmacct/processor/internal/model"
+	"github.com/pmacct/processor/internal/projection"
+	"github.com/pmacct/processor/internal/uploader"
+)
+
+// pipeline 一条 输入 -> 校验 -> 流处理 -> 滚动写入 -> 上传 的处理管道
+type pipeline struct {
+	cfg       config.PipelineConfig
+	dataDir   string
+	bw        *batchwriter.BatchWriter
+	up        *uploader.Uploader
+	dataChan  chan model.DataLine
+	errWriter *errorlog.LineWriter
+	flow      *flowProcessor         // 校验后、写入前的逐条处理（各管道共用）
+	proj      *projection.Projection // 本管道的输出列，未配置时为 nil（输出全部列）
+	input     *input                 // 默认管道读取 stdin，为 nil
+
+	ingestDone chan error
+	writerDone chan error
+}
+
+// pipelineDataDir 默认管道使用数据目录本身，命名管道使用 <data_dir>/pipelines/<name>
+func pipelineDataDir(root, name string) string {
+	if name == config.DefaultPipeline {
+		return root
+	}
+	return filepath.Join(root, "pipelines", name)
+}
+
+func newPipeline(ctx context.Context, pc config.PipelineConfig, dataDir string, chanCapacity int, flow *flowProcessor) (*pipeline, error) {
+	if err := config.EnsureDataDir(dataDir); err != nil {
+		return nil, err
+	}
+	p := &pipeline{
+		cfg:        pc,
+		dataDir:    dataDir,
+		bw:         batchwriter.NewBatchWriter(dataDir, pc.FilePrefix, pc.RotateIntervalSec, pc.RotateSizeMB),
+		up:         uploader.NewUploader(ctx, pc.Upload, dataDir, pc.UploadIntervalSec),
+		dataChan:   make(chan model.DataLine, chanCapacity),
+		flow:       flow,
+		ingestDone: make(chan error, 1),
+		writerDone: make(chan error, 1),
+	}
+	if len(pc.Columns) > 0 {
+		proj, err := projection.New(pc.Columns, flow.columns(), pc.Upload.Location)
+		if err != nil {
+			return nil, fmt.Errorf("管道 %s: %w", pc.Name, err)
+		}
+		p.proj = proj
+	}
+	p.bw.SetManifest(flow.manifest(pc, p.proj))
+	if pc.Input != config.InputStdin {
+		in, err := openInput(pc)
+		if err != nil {
+			return nil, err
+		}
+		p.input = in
+	}
+
+	// 错误行落盘
+	errWriter, err := errorlog.NewLineWriter(dataDir)
+	if err != nil {
+		slog.Error("初始化错误行写入器失败", "pipeline", pc.Name, "err", err)
+	}
+	p.errWriter = errWriter
+	return p, nil
+}
+
+// close 关闭当前文件与错误行写入器（writer goroutine 结束后调用）
+func (p *pipeline) close() {
+	if err := p.bw.Close(); err != nil {
+		slog.Error("关闭 batch writer 失败", "pipeline", p.cfg.Name, "err", err)
+	} else {
+		slog.Info("Batch writer 已关闭", "pipeline", p.cfg.Name)
+	}
+	if p.errWriter != nil {
+		if err := p.errWriter.Close(); err != nil {
+			slog.Error("关闭错误行写入器失败", "pipeline", p.cfg.Name, "err", err)
+		}
+	}
+}
+
+// pipelineSet 全部管道，下标 0 为默认管道
+type pipelineSet []*pipeline
+
+// rotate 滚动所有管道的当前文件
+func (ps pipelineSet) rotate() error {
+	var errs []error
+	for _, p := range ps {
+		if err := p.bw.Rotate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.cfg.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// triggerScan 触发所有管道立即扫描上传
+func (ps pipelineSet) triggerScan() {
+	for _, p := range ps {
+		p.up.TriggerScan()
+	}
+}
+
+// backlog 所有管道待上传文件的数量与总大小
+func (ps pipelineSet) backlog() (int, int64) {
+	var files int
+	var size int64
+	for _, p := range ps {
+		n, s := p.up.Backlog()
+		files += n
+		size += s
+	}
+	return files, size
+}
+
+// lastSuccess 所有管道中最近一次上传成功的时间
+func (ps pipelineSet) lastSuccess() time.Time {
+	var last time.Time
+	for _, p := range ps {
+		if t := p.up.LastSuccess(); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// channelUsage 所有管道数据通道的当前深度与容量之和
+func (ps pipelineSet) channelUsage() (int, int) {
+	var depth, capacity int
+	for _, p := range ps {
+		depth += len(p.dataChan)
+		capacity += cap(p.dataChan)
+	}
+	return depth, capacity
+}
+
+// names 所有管道名，逗号分隔
+func (ps pipelineSet) names() string {
+	names := make([]string, 0, len(ps))
+	for _, p := range ps {
+		names = append(names, p.cfg.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (ps pipelineSet) byName(name string) *pipeline {
+	for _, p := range ps {
+		if p.cfg.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// input 命名管道的输入端：命名管道（FIFO）或 Unix/TCP 监听
+type input struct {
+	kind string
+	addr string
+	fifo *os.File
+	ln   net.Listener
+}
+
+// openInput 启动时打开输入，地址被占用或不可用时直接报错
+func openInput(pc config.PipelineConfig) (*input, error) {
+	kind, addr := pc.InputKind()
+	in := &input{kind: kind, addr: addr}
+	switch kind {
+	case config.InputFIFO:
+		if err := ensureFIFO(addr); err != nil {
+			return nil, fmt.Errorf("管道 %s 准备 FIFO 失败: %w", pc.Name, err)
+		}
+		// 以读写方式打开：不阻塞等待写端，写端关闭后也不会读到 EOF，可接受 nfacctd 重启后重新写入
+		f, err := os.OpenFile(addr, os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("管道 %s 打开 FIFO 失败: %w", pc.Name, err)
+		}
+		in.fifo = f
+	case config.InputUnix:
+		// 清理上次退出残留的套接字文件
+		if fi, err := os.Lstat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(addr)
+		}
+		fallthrough
+	case config.InputTCP:
+		ln, err := net.Listen(kind, addr)
+		if err != nil {
+			return nil, fmt.Errorf("管道 %s 监听 %s 失败: %w", pc.Name, pc.Input, err)
+		}
+		in.ln = ln
+	default:
+		return nil, fmt.Errorf("管道 %s 不支持的输入: %s", pc.Name, pc.Input)
+	}
+	return in, nil
+}
+
+func ensureFIFO(path string) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return syscall.Mkfifo(path, 0660)
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return fmt.Errorf("%s 已存在且不是 FIFO", path)
+	}
+	return nil
+}
+
+// serve 持续读取输入直到 ctx 取消；套接字输入每个连接单独调用 handle
+func (in *input) serve(ctx context.Context, handle func(r io.Reader) error) error {
+	if in.fifo != nil {
+		go func() {
+			<-ctx.Done()
+			in.fifo.Close()
+		}()
+		err := handle(in.fifo)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		conns = make(map[net.Conn]struct{})
+	)
+	go func() {
+		<-ctx.Done()
+		in.ln.Close()
+		mu.Lock()
+		for c := range conns {
+			c.Close()
+		}
+		mu.Unlock()
+	}()
+	defer func() {
+		wg.Wait()
+		if in.kind == config.InputUnix {
+			_ = os.Remove(in.addr)
+		}
+	}()
+
+	for {
+		conn, err := in.ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+		slog.Info("输入连接已建立", "input", in.kind+":"+in.addr, "remote", conn.RemoteAddr().String())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := handle(conn); err != nil && ctx.Err() == nil {
+				slog.Warn("输入连接读取失败", "input", in.kind+":"+in.addr, "err", err)
+			}
+			conn.Close()
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
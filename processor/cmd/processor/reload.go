@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/diag"
+	"github.com/pmacct/processor/internal/filter"
+	"github.com/pmacct/processor/internal/remoteconfig"
+	"github.com/pmacct/processor/internal/statusreport"
+)
+
+// currentConfig 当前生效的配置（SIGHUP 热加载成功后替换）
+var currentConfig atomic.Pointer[config.ProcessorConfig]
+
+// restartOnlyKeys 修改后需重启才能生效的配置项；以 _ 结尾的按前缀匹配
+var restartOnlyKeys = []string{
+	"processor_file_prefix",
+	"processor_ingest_chan_capacity",
+	"processor_ingest_chan_timeout_ms",
+	"processor_debug_print_interval",
+	"processor_metrics_listen",
+	"processor_instance_id",
+	"processor_status_report_enabled",
+	"processor_diag_enabled",
+	"processor_admin_",
+	"processor_otlp_",
+	"processor_pipelines",
+	"processor_remote_config_",
+	"processor_geoip_",
+	"processor_zones",
+	"processor_zone_",
+	"processor_anonymize_",
+	"processor_sampling_",
+	"processor_output_columns",
+	"processor_service_",
+}
+
+// restartOnlyPipelineFields 命名管道中需重启才能生效的字段（processor_pipeline_<name>_<field>）
+var restartOnlyPipelineFields = []string{"input", "schema", "file_prefix", "columns"}
+
+// reloader 处理 SIGHUP 与远程配置更新：重新加载并校验配置，仅将可热更新的项应用到运行中的组件
+type reloader struct {
+	configPath string
+	pipes      pipelineSet
+	reporter   *statusreport.Reporter
+	diag       *diag.Collector
+	flow       *flowProcessor
+
+	mu      sync.Mutex
+	overlay atomic.Pointer[config.Overlay] // 当前生效的远程配置（未启用时为 nil）
+}
+
+func (rl *reloader) run(hup <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-hup:
+			rl.reload()
+		}
+	}
+}
+
+func (rl *reloader) reload() {
+	slog.Info("收到 SIGHUP，重新加载配置", "config", rl.configPath)
+	if err := rl.apply(rl.overlay.Load()); err != nil {
+		slog.Error("配置热加载失败，继续使用原配置", "err", err)
+	}
+}
+
+// applyRemote 应用远程下发的配置，校验与生效规则与 SIGHUP 相同，成功后成为当前叠加配置
+func (rl *reloader) applyRemote(o *config.Overlay) error {
+	slog.Info("收到新的远程配置，重新加载配置", "config", rl.configPath)
+	return rl.apply(o)
+}
+
+func (rl *reloader) apply(overlay *config.Overlay) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	next, err := config.LoadConfigWithOverlay(rl.configPath, overlay)
+	if err != nil {
+		return err
+	}
+	prev := currentConfig.Load()
+
+	// 采样率可能来自 pmacct.conf 的 sampling_rate（不在 processor_* 键值中），单独比较
+	if next.Sampling != prev.Sampling {
+		return fmt.Errorf("采样率校正配置变化（采样率 %d -> %d）%w，其余修改也未应用", prev.Sampling.Rate, next.Sampling.Rate, remoteconfig.ErrRestartRequired)
+	}
+
+	changed := diffKeys(prev.Values, next.Values)
+	if len(changed) == 0 {
+		rl.overlay.Store(overlay)
+		slog.Info("配置无变化")
+		return nil
+	}
+	if keys := restartRequired(changed); len(keys) > 0 {
+		return fmt.Errorf("以下配置项%w，其余修改也未应用: %s", remoteconfig.ErrRestartRequired, strings.Join(keys, ","))
+	}
+
+	// 先执行可能失败的部分，失败时整体不生效：无副作用的构建在前，
+	// 状态上报重载会替换客户端与队列，放在可能失败的步骤中的最后
+	var nextFilter *filter.Filter
+	filterChanged := anyPrefix(changed, "processor_filter")
+	if filterChanged {
+		if nextFilter, err = buildFilter(next.Filter); err != nil {
+			return err
+		}
+	}
+	if rl.reporter != nil && anyPrefix(changed, "processor_status_report_") {
+		if err := rl.reporter.Reload(next.StatusReport); err != nil {
+			return fmt.Errorf("状态上报配置无效: %w", err)
+		}
+	}
+
+	// 管道集合不可热变更，新旧配置中的管道按名称一一对应
+	uploadChanged := anyPrefix(changed, "processor_upload_", "processor_ftp_", "processor_timezone", "processor_pipeline_")
+	nextPipes := append([]config.PipelineConfig{next.DefaultPipeline()}, next.Pipelines...)
+	prevPipes := append([]config.PipelineConfig{prev.DefaultPipeline()}, prev.Pipelines...)
+	for i, np := range nextPipes {
+		p := rl.pipes.byName(np.Name)
+		if p == nil || i >= len(prevPipes) {
+			continue
+		}
+		pp := prevPipes[i]
+		if np.RotateIntervalSec != pp.RotateIntervalSec {
+			p.bw.SetRotateInterval(np.RotateIntervalSec)
+		}
+		if np.RotateSizeMB != pp.RotateSizeMB {
+			p.bw.SetRotateSize(np.RotateSizeMB)
+		}
+		if np.UploadIntervalSec != pp.UploadIntervalSec {
+			p.up.SetUploadInterval(np.UploadIntervalSec)
+		}
+		if uploadChanged {
+			p.up.UpdateConfig(np.Upload)
+		}
+	}
+	if filterChanged {
+		rl.flow.filters.Store(nextFilter)
+	}
+	if rl.diag != nil && next.Diag.IntervalSec != prev.Diag.IntervalSec {
+		rl.diag.SetInterval(next.Diag.IntervalSec)
+	}
+	if next.LogLevel != prev.LogLevel {
+		level := next.LogLevel
+		if level == "" {
+			level = *logLevel
+		}
+		if _, err := setLogLevel(level); err != nil {
+			slog.Warn("日志级别未调整", "err", err)
+		}
+	}
+
+	rl.overlay.Store(overlay)
+	currentConfig.Store(next)
+	slog.Info("配置热加载完成", "changes", describeChanges(changed, prev, next))
+	return nil
+}
+
+// diffKeys 返回新旧配置中取值不同（含新增、删除）的键，按字母序排列
+func diffKeys(prev, next map[string]string) []string {
+	var keys []string
+	for k, v := range next {
+		if old, ok := prev[k]; !ok || old != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func restartRequired(changed []string) []string {
+	var out []string
+	for _, k := range changed {
+		if isRestartOnly(k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func isRestartOnly(key string) bool {
+	for _, r := range restartOnlyKeys {
+		if key == r || (strings.HasSuffix(r, "_") && strings.HasPrefix(key, r)) {
+			return true
+		}
+	}
+	if strings.HasPrefix(key, "processor_pipeline_") {
+		for _, f := range restartOnlyPipelineFields {
+			if strings.HasSuffix(key, "_"+f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyPrefix(keys []string, prefixes ...string) bool {
+	for _, k := range keys {
+		for _, p := range prefixes {
+			if strings.HasPrefix(k, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// describeChanges 生成 "key: 旧值 -> 新值" 摘要，敏感取值已脱敏
+func describeChanges(keys []string, prev, next *config.ProcessorConfig) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		oldV, hadOld := prev.Values[k]
+		newV, hasNew := next.Values[k]
+		oldV, newV = prev.Redact(k, oldV), next.Redact(k, newV)
+		switch {
+		case !hadOld:
+			parts = append(parts, fmt.Sprintf("%s: (新增) %s", k, newV))
+		case !hasNew:
+			parts = append(parts, fmt.Sprintf("%s: (删除)", k))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %s -> %s", k, oldV, newV))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -3,10 +3,14 @@ module github.com/pmacct/processor
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/jlaffaye/ftp v0.2.0
+	github.com/oschwald/maxminddb-golang v1.13.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/hashicorp/errwrap v1.0.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 )
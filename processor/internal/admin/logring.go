@@ -0,0 +1,86 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogRecord 最近的告警/错误日志
+type LogRecord struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"msg"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// LogRing 保存最近 N 条 WARN 及以上日志，供 /status 展示
+type LogRing struct {
+	mu      sync.Mutex
+	size    int
+	records []LogRecord
+}
+
+// NewLogRing 创建容量为 size 的日志环
+func NewLogRing(size int) *LogRing {
+	return &LogRing{size: size}
+}
+
+// Recent 返回最近的日志（旧 -> 新）
+func (r *LogRing) Recent() []LogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]LogRecord(nil), r.records...)
+}
+
+func (r *LogRing) add(rec LogRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, rec)
+	if len(r.records) > r.size {
+		r.records = r.records[len(r.records)-r.size:]
+	}
+}
+
+// Wrap 包装 slog.Handler，在正常输出的同时记录 WARN 及以上日志
+func (r *LogRing) Wrap(h slog.Handler) slog.Handler {
+	return &ringHandler{next: h, ring: r}
+}
+
+type ringHandler struct {
+	next  slog.Handler
+	ring  *LogRing
+	attrs []slog.Attr
+}
+
+func (h *ringHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ringHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level >= slog.LevelWarn {
+		attrs := make(map[string]interface{}, rec.NumAttrs()+len(h.attrs))
+		for _, a := range h.attrs {
+			attrs[a.Key] = a.Value.Any()
+		}
+		rec.Attrs(func(a slog.Attr) bool {
+			v := a.Value.Any()
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			attrs[a.Key] = v
+			return true
+		})
+		h.ring.add(LogRecord{Time: rec.Time, Level: rec.Level.String(), Message: rec.Message, Attrs: attrs})
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ringHandler{next: h.next.WithAttrs(attrs), ring: h.ring, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	return &ringHandler{next: h.next.WithGroup(name), ring: h.ring, attrs: h.attrs}
+}
@@ -0,0 +1,199 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmacct/processor/internal/config"
+)
+
+// ActionFunc 处理 POST /actions/<name>，返回值序列化为 JSON 响应
+type ActionFunc func(r *http.Request) (interface{}, error)
+
+var (
+	// ErrUnavailable 动作当前不可用（如未启用对应功能），返回 409
+	ErrUnavailable = errors.New("动作当前不可用")
+	// ErrBadRequest 动作参数无效，返回 400
+	ErrBadRequest = errors.New("参数无效")
+)
+
+type readyCheck struct {
+	name  string
+	check func() error
+}
+
+// Server 本地管理接口：探针、状态查询与运行时动作
+type Server struct {
+	cfg config.AdminConfig
+	mux *http.ServeMux
+
+	mu      sync.Mutex
+	checks  []readyCheck
+	status  func() map[string]interface{}
+	actions map[string]ActionFunc
+}
+
+// NewServer 创建管理接口（未启用时返回 nil）
+func NewServer(cfg config.AdminConfig) *Server {
+	if !cfg.Enabled {
+		return nil
+	}
+	s := &Server{
+		cfg:     cfg,
+		mux:     http.NewServeMux(),
+		actions: map[string]ActionFunc{},
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	s.mux.Handle("/status", s.requireToken(http.HandlerFunc(s.handleStatus)))
+	s.mux.Handle("/actions/", s.requireToken(http.HandlerFunc(s.handleAction)))
+	return s
+}
+
+// AddReadyCheck 注册一项就绪检查，需在 Run() 之前调用
+func (s *Server) AddReadyCheck(name string, check func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, readyCheck{name: name, check: check})
+}
+
+// SetStatus 设置 /status 的内容来源，需在 Run() 之前调用
+func (s *Server) SetStatus(fn func() map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = fn
+}
+
+// HandleAction 注册 POST /actions/<name>，需在 Run() 之前调用
+func (s *Server) HandleAction(name string, fn ActionFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions[name] = fn
+}
+
+// Handle 挂载额外的只读接口（如 /metrics），同样受 token 保护
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, s.requireToken(h))
+}
+
+// Run 启动监听，ctx 取消后关闭
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.cfg.Listen,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	slog.Info("管理接口已启动", "addr", s.cfg.Listen, "token", s.cfg.Token != "")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	if s.cfg.Token == "" {
+		return next
+	}
+	want := []byte(s.cfg.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Admin-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "未授权"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	checks := append([]readyCheck(nil), s.checks...)
+	s.mu.Unlock()
+
+	results := make(map[string]string, len(checks))
+	ready := true
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			results[c.name] = err.Error()
+			ready = false
+			continue
+		}
+		results[c.name] = "ok"
+	}
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{"ready": ready, "checks": results})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	fn := s.status
+	s.mu.Unlock()
+	if fn == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	writeJSON(w, http.StatusOK, fn())
+}
+
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 POST"})
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/actions/")
+	s.mu.Lock()
+	fn, ok := s.actions[name]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "未知的动作: " + name})
+		return
+	}
+	result, err := fn(r)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUnavailable):
+			code = http.StatusConflict
+		case errors.Is(err, ErrBadRequest):
+			code = http.StatusBadRequest
+		}
+		slog.Warn("管理接口动作失败", "action", name, "err", err)
+		writeJSON(w, code, map[string]string{"error": err.Error()})
+		return
+	}
+	slog.Info("管理接口动作已执行", "action", name, "remote", r.RemoteAddr)
+	if result == nil {
+		result = map[string]string{"status": "ok"}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
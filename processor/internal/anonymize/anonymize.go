@@ -0,0 +1,113 @@
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/iptrie"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// Anonymizer 写入前替换流记录中的 SRC_IP/DST_IP，豁免前缀内的地址保持不变
+type Anonymizer struct {
+	cfg         config.AnonymizeConfig
+	cp          *cryptoPAn
+	exempt      *iptrie.Trie[struct{}]
+	fingerprint string // 密钥指纹（sha256 前 8 字节），用于 manifest 标识所用密钥
+}
+
+// New 按配置创建匿名化器；cryptopan 模式在此读取密钥文件
+func New(cfg config.AnonymizeConfig) (*Anonymizer, error) {
+	a := &Anonymizer{cfg: cfg, exempt: iptrie.New[struct{}]()}
+	for _, p := range cfg.Exempt {
+		a.exempt.Insert(p, struct{}{})
+	}
+	if cfg.Mode == config.AnonymizeCryptoPAn {
+		key, err := readKey(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if a.cp, err = newCryptoPAn(key); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		a.fingerprint = "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return a, nil
+}
+
+// readKey 读取密钥文件：32 字节原始密钥，或 64 位十六进制文本（首尾空白会被去除）
+func readKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取匿名化密钥文件失败: %w", err)
+	}
+	if len(data) == 32 {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if len(text) == 64 {
+		if key, err := hex.DecodeString(text); err == nil {
+			return key, nil
+		}
+	}
+	if len(text) == 32 {
+		return []byte(text), nil
+	}
+	return nil, fmt.Errorf("匿名化密钥文件 %s 需为 32 字节密钥或 64 位十六进制", path)
+}
+
+// Apply 匿名化流记录的两端地址（同时更新原始列与解析值）
+func (a *Anonymizer) Apply(f *model.Flow) {
+	f.SrcIP = a.addr(f.SrcIP)
+	f.DstIP = a.addr(f.DstIP)
+	f.Fields[model.ColSrcIP] = f.SrcIP.String()
+	f.Fields[model.ColDstIP] = f.DstIP.String()
+}
+
+func (a *Anonymizer) addr(ip netip.Addr) netip.Addr {
+	if a.exempt.Contains(ip) {
+		metrics.AnonymizedAddrs.Inc(metrics.AnonExempt)
+		return ip
+	}
+	metrics.AnonymizedAddrs.Inc(metrics.AnonApplied)
+	switch a.cfg.Mode {
+	case config.AnonymizeCryptoPAn:
+		return a.cp.anonymize(ip.Unmap())
+	case config.AnonymizeTruncate:
+		bits := a.cfg.IPv6Prefix
+		if ip.Unmap().Is4() {
+			ip, bits = ip.Unmap(), a.cfg.IPv4Prefix
+		}
+		p, _ := ip.Prefix(bits)
+		return p.Addr()
+	}
+	return ip
+}
+
+// Policy 匿名化策略描述（写入 manifest 与 /status，不含密钥本身）
+func (a *Anonymizer) Policy() map[string]interface{} {
+	out := map[string]interface{}{
+		"mode":   a.cfg.Mode,
+		"fields": []string{"SRC_IP", "DST_IP"},
+	}
+	switch a.cfg.Mode {
+	case config.AnonymizeCryptoPAn:
+		out["key_fingerprint"] = a.fingerprint
+	case config.AnonymizeTruncate:
+		out["ipv4_prefix"] = a.cfg.IPv4Prefix
+		out["ipv6_prefix"] = a.cfg.IPv6Prefix
+	}
+	exempt := make([]string, 0, len(a.cfg.Exempt))
+	for _, p := range a.cfg.Exempt {
+		exempt = append(exempt, p.String())
+	}
+	out["exempt"] = exempt
+	return out
+}
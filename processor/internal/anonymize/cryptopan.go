@@ -0,0 +1,55 @@
+package anonymize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"net/netip"
+)
+
+// cryptoPAn 保留前缀关系的地址匿名化（Xu 等人的 Crypto-PAn）：
+// 两个地址共享前 n 位当且仅当匿名化后的地址共享前 n 位。
+// IPv4 与参考实现逐位一致；IPv6 按同一方法扩展到 128 位
+type cryptoPAn struct {
+	block cipher.Block
+	pad   [16]byte
+}
+
+// newCryptoPAn key 为 32 字节：前 16 字节为 AES-128 密钥，后 16 字节经加密后作为填充
+func newCryptoPAn(key []byte) (*cryptoPAn, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("Crypto-PAn 密钥需为 32 字节，实际 %d 字节", len(key))
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	c := &cryptoPAn{block: block}
+	block.Encrypt(c.pad[:], key[16:])
+	return c, nil
+}
+
+// anonymize 第 i 位的结果 = 原地址第 i 位 XOR AES(原地址前 i 位 + 填充剩余位) 的最高位
+func (c *cryptoPAn) anonymize(addr netip.Addr) netip.Addr {
+	orig := addr.AsSlice()
+	nbits := len(orig) * 8
+	out := make([]byte, len(orig))
+	var in, enc [16]byte
+	for pos := 0; pos < nbits; pos++ {
+		// 前 pos 位取原地址，其余取填充
+		in = c.pad
+		full := pos / 8
+		copy(in[:full], orig[:full])
+		if rem := pos % 8; rem > 0 {
+			mask := byte(0xff) << (8 - rem)
+			in[full] = orig[full]&mask | c.pad[full]&^mask
+		}
+		c.block.Encrypt(enc[:], in[:])
+		out[pos/8] |= (enc[0] >> 7) << (7 - pos%8)
+	}
+	for i := range out {
+		out[i] ^= orig[i]
+	}
+	res, _ := netip.AddrFromSlice(out)
+	return res
+}
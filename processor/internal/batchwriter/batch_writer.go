@@ -9,7 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pmacct/processor/internal/metrics"
 	"github.com/pmacct/processor/internal/model"
+	"github.com/pmacct/processor/internal/otlp"
 )
 
 // BatchWriter 负责批量写入数据到文件
@@ -19,16 +21,19 @@ type BatchWriter struct {
 	rotateIntervalSec int
 	rotateSizeMB      int
 
-	buffer     *bufio.Writer
-	gzipWriter *gzip.Writer
-	file       *os.File
+	buffer      *bufio.Writer
+	gzipWriter  *gzip.Writer
+	file        *os.File
 	currentPath string
 
 	writtenBytes int64
+	writtenLines int64
 	startTime    time.Time
 	fileIndex    int
 
-	mu sync.Mutex
+	manifest map[string]interface{} // 非 nil 时每个数据文件附带 manifest
+
+	mu     sync.Mutex
 	closed bool
 }
 
@@ -70,6 +75,7 @@ func (bw *BatchWriter) WriteBatch(lines []model.DataLine) error {
 		bw.writtenBytes += int64(len(data))
 		_ = n // 避免未使用变量警告
 	}
+	bw.writtenLines += int64(len(lines))
 
 	// 检查是否需要滚动
 	if bw.shouldRotate() {
@@ -93,6 +99,20 @@ func (bw *BatchWriter) Flush() error {
 	return bw.buffer.Flush()
 }
 
+// SetRotateInterval 运行时调整滚动时间间隔（秒），下次写入时生效
+func (bw *BatchWriter) SetRotateInterval(sec int) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	bw.rotateIntervalSec = sec
+}
+
+// SetRotateSize 运行时调整滚动大小阈值（MB），下次写入时生效
+func (bw *BatchWriter) SetRotateSize(sizeMB int) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	bw.rotateSizeMB = sizeMB
+}
+
 // shouldRotate 检查是否应该滚动文件
 func (bw *BatchWriter) shouldRotate() bool {
 	// 检查时间间隔
@@ -147,18 +167,25 @@ func (bw *BatchWriter) closeAndRenameCurrentFile() error {
 	// 将 .part 文件重命名为 .csv.gz
 	if bw.currentPath != "" {
 		// 确保路径长度足够并且以 .part 结尾
+		finalPath := bw.currentPath + ".csv.gz"
 		if len(bw.currentPath) >= 5 && bw.currentPath[len(bw.currentPath)-5:] == ".part" {
-			finalPath := bw.currentPath[:len(bw.currentPath)-5] + ".csv.gz"
-			if err := os.Rename(bw.currentPath, finalPath); err != nil {
-				return fmt.Errorf("重命名文件失败: %w", err)
-			}
-		} else {
-			// 如果不是以 .part 结尾，添加 .csv.gz 后缀
-			finalPath := bw.currentPath + ".csv.gz"
-			if err := os.Rename(bw.currentPath, finalPath); err != nil {
-				return fmt.Errorf("重命名文件失败: %w", err)
+			finalPath = bw.currentPath[:len(bw.currentPath)-5] + ".csv.gz"
+		}
+		// 先写 manifest 再发布数据文件：manifest 写入失败时数据文件保留为 .part，不会被上传
+		if bw.manifest != nil {
+			if err := bw.writeManifest(finalPath); err != nil {
+				otlp.FileSpan(finalPath, otlp.SpanFileRotated, bw.startTime, time.Now(), err, nil)
+				return fmt.Errorf("写入 manifest 失败，数据文件未发布（%s）: %w", bw.currentPath, err)
 			}
 		}
+		if err := os.Rename(bw.currentPath, finalPath); err != nil {
+			otlp.FileSpan(finalPath, otlp.SpanFileRotated, bw.startTime, time.Now(), err, nil)
+			return fmt.Errorf("重命名文件失败: %w", err)
+		}
+		metrics.Rotations.Inc()
+		otlp.FileSpan(finalPath, otlp.SpanFileRotated, bw.startTime, time.Now(), nil, map[string]interface{}{
+			"file.raw_bytes": bw.writtenBytes,
+		})
 	}
 
 	return nil
@@ -184,11 +211,14 @@ func (bw *BatchWriter) rotateFile() error {
 	// 创建带缓冲的 writer（使用 4MB 缓冲区）
 	buffer := bufio.NewWriterSize(gzipWriter, 4*1024*1024)
 
+	otlp.FileSpan(filename, otlp.SpanFileCreated, now, now, nil, nil)
+
 	bw.file = file
 	bw.gzipWriter = gzipWriter
 	bw.buffer = buffer
 	bw.startTime = now
 	bw.writtenBytes = 0
+	bw.writtenLines = 0
 	bw.fileIndex++
 
 	return nil
@@ -220,7 +250,41 @@ func (bw *BatchWriter) Close() error {
 	return nil
 }
 
+// Rotate 强制滚动当前文件（无打开文件时不做任何事）
+func (bw *BatchWriter) Rotate() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if bw.closed {
+		return fmt.Errorf("batch writer 已关闭")
+	}
+	if bw.file == nil {
+		return nil
+	}
+	return bw.flushAndRotate()
+}
+
+// CurrentFile 返回当前写入中的文件路径（无打开文件时为空）
+func (bw *BatchWriter) CurrentFile() string {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if bw.file == nil {
+		return ""
+	}
+	return bw.currentPath
+}
+
+// CurrentFileStats 返回当前文件已写入的原始字节数与文件年龄（无打开文件时 ok=false）
+func (bw *BatchWriter) CurrentFileStats() (int64, time.Duration, bool) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if bw.file == nil {
+		return 0, 0, false
+	}
+	return bw.writtenBytes, time.Since(bw.startTime), true
+}
+
 // GetDataDir 返回数据目录路径
 func (bw *BatchWriter) GetDataDir() string {
 	return bw.dataDir
-}
\ No newline at end of file
+}
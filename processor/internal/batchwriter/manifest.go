@@ -0,0 +1,54 @@
+package batchwriter
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestSuffix 与数据文件同名的 manifest（上传器按 .manifest. 归类为 manifests）
+const manifestSuffix = ".manifest.json.gz"
+
+// SetManifest 设置每个数据文件附带的 manifest 固定内容（输出列、匿名化策略等），nil 表示不写 manifest
+func (bw *BatchWriter) SetManifest(meta map[string]interface{}) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	bw.manifest = meta
+}
+
+// writeManifest 在数据文件重命名为最终文件名之前写入同名 manifest（先写 .part 再重命名）
+func (bw *BatchWriter) writeManifest(dataPath string) error {
+	doc := make(map[string]interface{}, len(bw.manifest)+5)
+	for k, v := range bw.manifest {
+		doc[k] = v
+	}
+	doc["file"] = filepath.Base(dataPath)
+	doc["lines"] = bw.writtenLines
+	doc["raw_bytes"] = bw.writtenBytes
+	doc["started_at"] = bw.startTime.UTC().Format(time.RFC3339)
+	doc["closed_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	finalPath := strings.TrimSuffix(dataPath, ".csv.gz") + manifestSuffix
+	tmpPath := finalPath + ".part"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("创建 manifest 失败: %w", err)
+	}
+	zw := gzip.NewWriter(f)
+	encErr := json.NewEncoder(zw).Encode(doc)
+	if err := zw.Close(); encErr == nil {
+		encErr = err
+	}
+	if err := f.Close(); encErr == nil {
+		encErr = err
+	}
+	if encErr != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入 manifest 失败: %w", encErr)
+	}
+	return os.Rename(tmpPath, finalPath)
+}
@@ -0,0 +1,172 @@
+// Package classify 按 (协议, 端口) 识别流的服务与应用类别
+package classify
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pmacct/processor/internal/metrics"
+)
+
+//go:embed services.csv
+var builtinTable []byte
+
+// Unknown 未识别的服务与类别
+const Unknown = "unknown"
+
+// Service 识别结果
+type Service struct {
+	Name     string
+	Category string
+}
+
+type key struct {
+	proto uint8
+	port  uint16
+}
+
+// portProtocols 带端口的协议（TCP、UDP、SCTP），其余协议按 port 0 整体归类
+var portProtocols = map[uint8]bool{6: true, 17: true, 132: true}
+
+// Classifier 服务表：内置表 < processor_service_file < processor_service_overrides，后者覆盖前者
+type Classifier struct {
+	table map[key]Service
+}
+
+// New 加载内置表、可选的服务表文件（格式同内置表：proto,port,service,category）与覆盖项
+// （proto[/port]=service[:category]，如 tcp/8443=admin-ui:web）
+func New(file string, overrides []string) (*Classifier, error) {
+	c := &Classifier{table: make(map[key]Service)}
+	if err := c.load(builtinTable, "内置服务表"); err != nil {
+		return nil, err
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取服务表失败: %w", err)
+		}
+		if err := c.load(data, file); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range overrides {
+		target, svc, ok := strings.Cut(o, "=")
+		if !ok {
+			return nil, fmt.Errorf("服务覆盖项 %q 应为 proto[/port]=service[:category]", o)
+		}
+		proto, port, _ := strings.Cut(target, "/")
+		name, category, _ := strings.Cut(svc, ":")
+		if err := c.add(proto, port, name, category); err != nil {
+			return nil, fmt.Errorf("服务覆盖项 %q: %w", o, err)
+		}
+	}
+	return c, nil
+}
+
+// load 逐行读取 CSV 服务表，# 开头为注释
+func (c *Classifier) load(data []byte, source string) error {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, ",")
+		if len(f) < 3 {
+			return fmt.Errorf("%s 第 %d 行: 应为 proto,port,service,category", source, n)
+		}
+		category := ""
+		if len(f) > 3 {
+			category = f[3]
+		}
+		if err := c.add(f[0], f[1], f[2], category); err != nil {
+			return fmt.Errorf("%s 第 %d 行: %w", source, n, err)
+		}
+	}
+	return sc.Err()
+}
+
+func (c *Classifier) add(proto, port, name, category string) error {
+	p, err := parseProto(strings.TrimSpace(proto))
+	if err != nil {
+		return err
+	}
+	var k key
+	k.proto = p
+	if port = strings.TrimSpace(port); port != "" && port != "0" {
+		if !portProtocols[p] {
+			return fmt.Errorf("协议 %s 没有端口", proto)
+		}
+		v, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return fmt.Errorf("无效的端口 %s", port)
+		}
+		k.port = uint16(v)
+	}
+	name, category = strings.ToLower(strings.TrimSpace(name)), strings.ToLower(strings.TrimSpace(category))
+	if name == "" {
+		return fmt.Errorf("服务名不能为空")
+	}
+	if category == "" {
+		// 覆盖项未写类别时沿用表中已有的类别
+		category = Unknown
+		if old, ok := c.table[k]; ok {
+			category = old.Category
+		}
+	}
+	c.table[k] = Service{Name: name, Category: category}
+	return nil
+}
+
+// parseProto 协议号或协议名（tcp、udp、icmp 等）
+func parseProto(s string) (uint8, error) {
+	if v, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return uint8(v), nil
+	}
+	if v, ok := metrics.ProtoNumber(strings.ToLower(s)); ok {
+		return uint8(v), nil
+	}
+	return 0, fmt.Errorf("未知的协议 %s", s)
+}
+
+// Classify 识别一条流的服务：两端端口都在表中时取较小的端口作为服务端，只有一端在表中时取该端；
+// 端口均未识别时按协议整体归类，仍未识别则返回 unknown
+func (c *Classifier) Classify(proto uint8, srcPort, dstPort uint16) Service {
+	if portProtocols[proto] {
+		src, srcOK := c.lookup(proto, srcPort)
+		dst, dstOK := c.lookup(proto, dstPort)
+		switch {
+		case srcOK && dstOK:
+			if srcPort < dstPort {
+				return src
+			}
+			return dst
+		case dstOK:
+			return dst
+		case srcOK:
+			return src
+		}
+	}
+	if s, ok := c.table[key{proto: proto}]; ok {
+		return s
+	}
+	return Service{Name: Unknown, Category: Unknown}
+}
+
+func (c *Classifier) lookup(proto uint8, port uint16) (Service, bool) {
+	if port == 0 {
+		return Service{}, false
+	}
+	s, ok := c.table[key{proto, port}]
+	return s, ok
+}
+
+// Len 服务表条目数
+func (c *Classifier) Len() int {
+	return len(c.table)
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/pmacct/processor/internal/iptrie"
+)
+
+// 地址匿名化方式
+const (
+	AnonymizeNone      = "none"
+	AnonymizeCryptoPAn = "cryptopan" // 保留前缀关系的 Crypto-PAn（需密钥）
+	AnonymizeTruncate  = "truncate"  // 截断为前缀（如 IPv4 /24），主机位清零
+)
+
+// AnonymizeConfig 写入前对 SRC_IP/DST_IP 做匿名化（Mode 为 none 则不启用）
+type AnonymizeConfig struct {
+	Mode       string
+	KeyFile    string         // Crypto-PAn 密钥文件：32 字节原始密钥或 64 位十六进制
+	IPv4Prefix int            // truncate 保留的 IPv4 前缀长度
+	IPv6Prefix int            // truncate 保留的 IPv6 前缀长度
+	Exempt     []netip.Prefix // 不做匿名化的前缀
+}
+
+// Enabled 是否启用匿名化
+func (a AnonymizeConfig) Enabled() bool {
+	return a.Mode != "" && a.Mode != AnonymizeNone
+}
+
+// parseAnonymize 解析 processor_anonymize_*（默认值在 validateAnonymize 中填充）
+func parseAnonymize(kv map[string]string) (AnonymizeConfig, error) {
+	a := AnonymizeConfig{
+		Mode:    strings.ToLower(strings.TrimSpace(kv[processorPrefix+"anonymize_mode"])),
+		KeyFile: kv[processorPrefix+"anonymize_key_file"],
+	}
+	for _, f := range []struct {
+		key string
+		dst *int
+	}{
+		{"anonymize_ipv4_prefix", &a.IPv4Prefix},
+		{"anonymize_ipv6_prefix", &a.IPv6Prefix},
+	} {
+		if v, ok := kv[processorPrefix+f.key]; ok {
+			num, err := strconv.Atoi(v)
+			if err != nil {
+				return a, fmt.Errorf("processor_%s 不是整数: %w", f.key, err)
+			}
+			*f.dst = num
+		}
+	}
+	for _, s := range strings.Split(kv[processorPrefix+"anonymize_exempt"], ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err == nil {
+			p, err = iptrie.UnmapPrefix(p)
+		}
+		if err != nil {
+			return a, fmt.Errorf("processor_anonymize_exempt 中的前缀无效: %w", err)
+		}
+		a.Exempt = append(a.Exempt, p)
+	}
+	return a, nil
+}
+
+func validateAnonymize(a *AnonymizeConfig) error {
+	switch a.Mode {
+	case "", AnonymizeNone:
+		a.Mode = AnonymizeNone
+		return nil
+	case AnonymizeCryptoPAn:
+		if a.KeyFile == "" {
+			return fmt.Errorf("processor_anonymize_mode 为 cryptopan 时需配置 processor_anonymize_key_file")
+		}
+	case AnonymizeTruncate:
+		if a.IPv4Prefix == 0 {
+			a.IPv4Prefix = 24
+		}
+		if a.IPv6Prefix == 0 {
+			a.IPv6Prefix = 48
+		}
+		if a.IPv4Prefix < 0 || a.IPv4Prefix > 32 {
+			return fmt.Errorf("processor_anonymize_ipv4_prefix 需在 0-32 之间: %d", a.IPv4Prefix)
+		}
+		if a.IPv6Prefix < 0 || a.IPv6Prefix > 128 {
+			return fmt.Errorf("processor_anonymize_ipv6_prefix 需在 0-128 之间: %d", a.IPv6Prefix)
+		}
+	default:
+		return fmt.Errorf("processor_anonymize_mode 仅支持 none|cryptopan|truncate: %s", a.Mode)
+	}
+	return nil
+}
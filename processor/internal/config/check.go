@@ -0,0 +1,510 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// knownKeys 全部受支持的 processor_* 配置项（新增配置项时需同步登记，check-config 据此识别拼写错误）
+var knownKeys = map[string]bool{
+	"processor_config_file":                       true,
+	"processor_ftp_host":                          true,
+	"processor_ftp_port":                          true,
+	"processor_ftp_user":                          true,
+	"processor_ftp_pass":                          true,
+	"processor_ftp_dir":                           true,
+	"processor_ftp_timeout":                       true,
+	"processor_file_prefix":                       true,
+	"processor_timezone":                          true,
+	"processor_log_level":                         true,
+	"processor_rotate_interval_sec":               true,
+	"processor_rotate_size_mb":                    true,
+	"processor_upload_interval_sec":               true,
+	"processor_upload_targets":                    true,
+	"processor_pipelines":                         true,
+	"processor_upload_policy":                     true,
+	"processor_upload_path_template":              true,
+	"processor_upload_windows":                    true,
+	"processor_upload_rate_limit_kbps":            true,
+	"processor_upload_lease_sec":                  true,
+	"processor_upload_temp_max_age_sec":           true,
+	"processor_upload_retention_days":             true,
+	"processor_upload_retention_max_mb":           true,
+	"processor_upload_retention_dry_run":          true,
+	"processor_debug_print_interval":              true,
+	"processor_ingest_chan_capacity":              true,
+	"processor_ingest_chan_timeout_ms":            true,
+	"processor_instance_id":                       true,
+	"processor_metrics_listen":                    true,
+	"processor_diag_enabled":                      true,
+	"processor_diag_interval_sec":                 true,
+	"processor_admin_enabled":                     true,
+	"processor_admin_listen":                      true,
+	"processor_admin_token":                       true,
+	"processor_admin_input_stale_sec":             true,
+	"processor_status_report_enabled":             true,
+	"processor_status_report_url":                 true,
+	"processor_status_report_uuid":                true,
+	"processor_status_report_interval_sec":        true,
+	"processor_status_report_file_path":           true,
+	"processor_status_report_file_max_mb":         true,
+	"processor_status_report_file_backups":        true,
+	"processor_status_report_queue_max_mb":        true,
+	"processor_status_report_queue_max_age_sec":   true,
+	"processor_status_report_schema_version":      true,
+	"processor_status_report_token_file":          true,
+	"processor_status_report_hmac_key_file":       true,
+	"processor_status_report_tls_cert_file":       true,
+	"processor_status_report_tls_key_file":        true,
+	"processor_status_report_tls_ca_file":         true,
+	"processor_status_report_commands_enabled":    true,
+	"processor_status_report_command_key_file":    true,
+	"processor_status_report_command_max_age_sec": true,
+	"processor_status_report_audit_log":           true,
+	"processor_otlp_endpoint":                     true,
+	"processor_otlp_headers":                      true,
+	"processor_otlp_service_name":                 true,
+	"processor_otlp_interval_sec":                 true,
+	"processor_otlp_timeout_sec":                  true,
+	"processor_otlp_metrics_enabled":              true,
+	"processor_otlp_traces_enabled":               true,
+	"processor_remote_config_url":                 true,
+	"processor_remote_config_interval_sec":        true,
+	"processor_remote_config_timeout_sec":         true,
+	"processor_remote_config_token_file":          true,
+	"processor_remote_config_signing_key_file":    true,
+	"processor_geoip_city_db":                     true,
+	"processor_geoip_asn_db":                      true,
+	"processor_geoip_fields":                      true,
+	"processor_geoip_language":                    true,
+	"processor_geoip_cache_size":                  true,
+	"processor_geoip_check_interval_sec":          true,
+	"processor_zones":                             true,
+	"processor_zone_labels":                       true,
+	"processor_anonymize_mode":                    true,
+	"processor_anonymize_key_file":                true,
+	"processor_anonymize_ipv4_prefix":             true,
+	"processor_anonymize_ipv6_prefix":             true,
+	"processor_anonymize_exempt":                  true,
+	"processor_sampling_rate":                     true,
+	"processor_sampling_per_exporter":             true,
+	"processor_filter_mode":                       true,
+	"processor_filters":                           true,
+	"processor_output_columns":                    true,
+	"processor_service_columns":                   true,
+	"processor_service_file":                      true,
+	"processor_service_overrides":                 true,
+}
+
+// uploadTargetFields processor_upload_target_<name>_<field> 支持的字段
+var uploadTargetFields = []string{"host", "port", "user", "pass", "dir", "timeout"}
+
+// UnknownKey 未识别的配置项及最相近的已知配置项（可能为空）
+type UnknownKey struct {
+	Key        string
+	Suggestion string
+}
+
+// UnknownKeys 返回 kv 中未被识别的 processor_* 配置项（按字母序）
+func UnknownKeys(kv map[string]string) []UnknownKey {
+	var out []UnknownKey
+	for k := range kv {
+		if knownKeys[k] || isUploadTargetKey(k) || isPipelineKey(k) || isZoneKey(k) || isFilterKey(k) {
+			continue
+		}
+		out = append(out, UnknownKey{Key: k, Suggestion: suggestKey(k)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func isUploadTargetKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, processorPrefix+"upload_target_")
+	if !ok {
+		return false
+	}
+	for _, f := range uploadTargetFields {
+		if name, ok := strings.CutSuffix(rest, "_"+f); ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestKey 返回编辑距离足够近的已知配置项
+func suggestKey(key string) string {
+	best, bestDist := "", len(key)/3+1
+	for k := range knownKeys {
+		if d := editDistance(key, k); d < bestDist || (d == bestDist && best != "" && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// requiredAggregate processor 期望的 aggregate 原语及其对应的 CSV 列
+// 与 validator.ValidateLine 的 11 列一致（TIMESTAMP_MIN/MAX 由 nfacctd_stitching 产生，PACKETS/BYTES 总是输出）
+var requiredAggregate = []struct {
+	primitive string
+	column    string
+}{
+	{"src_host", "SRC_IP"},
+	{"dst_host", "DST_IP"},
+	{"src_port", "SRC_PORT"},
+	{"dst_port", "DST_PORT"},
+	{"tcpflags", "TCP_FLAGS"},
+	{"proto", "PROTOCOL"},
+	{"tos", "TOS"},
+}
+
+// optionalAggregate 可选原语及其列名：processor 按表头识别并在校验前移除该列
+var optionalAggregate = map[string]string{
+	"sampling_rate": "SAMPLING_RATE",
+}
+
+// requiredSettings nfacctd print 插件输出 processor 可解析的 CSV 所需的设置及原因
+var requiredSettings = []struct {
+	key    string
+	value  string
+	reason string
+}{
+	{"print_output", "csv", "processor 仅解析 CSV 输出"},
+	{"timestamps_since_epoch", "true", "TIMESTAMP_MIN/MAX 需为 epoch 秒"},
+	{"print_num_protos", "true", "PROTOCOL 需为协议号"},
+	{"nfacctd_stitching", "true", "缺少 TIMESTAMP_MIN/MAX 列"},
+}
+
+// printPluginNames start_collector 强制使用未命名的 print 插件，可能出现的作用域写法
+var printPluginNames = []string{"print", "default"}
+
+// parsePmacctSettings 解析 pmacct.conf 中非 processor_* 的 key: value 行
+// key[plugin]: value 形式的插件作用域设置以 "key[plugin]" 保存
+func parsePmacctSettings(content string) map[string]string {
+	kv := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.ReplaceAll(key, " ", ""))
+		if key == "" || strings.HasPrefix(key, processorPrefix) {
+			continue
+		}
+		kv[key] = unquote(val)
+	}
+	return kv
+}
+
+// printSetting 返回 print 插件生效的取值：插件作用域优先，其次全局
+func printSetting(kv map[string]string, key string) (string, bool) {
+	for _, name := range printPluginNames {
+		if v, ok := kv[key+"["+name+"]"]; ok {
+			return v, true
+		}
+	}
+	v, ok := kv[key]
+	return v, ok
+}
+
+// CheckPmacctSettings 校验 pmacct.conf 中 aggregate/print_* 等设置能否产生 processor 可处理的列集合，返回发现的问题
+func CheckPmacctSettings(content string) []string {
+	kv := parsePmacctSettings(content)
+	var problems []string
+
+	aggregate, ok := printSetting(kv, "aggregate")
+	if !ok {
+		problems = append(problems, "未设置 aggregate")
+	} else {
+		got := make(map[string]bool)
+		for _, p := range strings.Split(aggregate, ",") {
+			if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+				got[p] = true
+			}
+		}
+		for _, r := range requiredAggregate {
+			if !got[r.primitive] {
+				problems = append(problems, fmt.Sprintf("aggregate 缺少 %s（%s 列）", r.primitive, r.column))
+			}
+			delete(got, r.primitive)
+		}
+		for p := range optionalAggregate {
+			delete(got, p)
+		}
+		extra := make([]string, 0, len(got))
+		for p := range got {
+			extra = append(extra, p)
+		}
+		sort.Strings(extra)
+		for _, p := range extra {
+			problems = append(problems, fmt.Sprintf("aggregate 含有 processor 不支持的原语 %s（会产生额外列）", p))
+		}
+	}
+
+	for _, r := range requiredSettings {
+		v, _ := printSetting(kv, r.key)
+		if strings.ToLower(strings.TrimSpace(v)) != r.value {
+			problems = append(problems, fmt.Sprintf("%s 需为 %s（当前 %q）：%s", r.key, r.value, v, r.reason))
+		}
+	}
+	if v, ok := printSetting(kv, "print_output_separator"); ok && v != "," {
+		problems = append(problems, fmt.Sprintf("print_output_separator 需为 \",\"（当前 %q）", v))
+	}
+	if v, ok := printSetting(kv, "print_output_file"); ok && v != "" {
+		problems = append(problems, "不能设置 print_output_file：processor 从 nfacctd 标准输出读取数据")
+	}
+	return problems
+}
+
+// CheckResult check-config 的检查结果
+type CheckResult struct {
+	Config      *ProcessorConfig // 配置加载失败时为 nil
+	LoadErr     error
+	UnknownKeys []UnknownKey
+	Problems    []string // pmacct 设置问题
+}
+
+// OK 没有发现任何问题
+func (r *CheckResult) OK() bool {
+	return r.LoadErr == nil && len(r.UnknownKeys) == 0 && len(r.Problems) == 0
+}
+
+// CheckFile 完整检查配置文件：processor_* 配置加载与校验、未知配置项、pmacct 输出列设置
+func CheckFile(configPath string) (*CheckResult, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	res := &CheckResult{}
+	if _, structured := structuredFormat(configPath); !structured {
+		res.Problems = CheckPmacctSettings(string(content))
+	}
+	res.Config, res.LoadErr = LoadConfig(configPath)
+	if res.Config != nil {
+		res.UnknownKeys = UnknownKeys(res.Config.Values)
+	} else if src, err := readSources(configPath); err == nil {
+		applyEnvOverrides(src.kv, os.Environ())
+		res.UnknownKeys = UnknownKeys(src.kv)
+	}
+	return res, nil
+}
+
+// Effective 返回填充默认值后的生效配置（以 processor_* 键表示，敏感项已脱敏）
+func (c *ProcessorConfig) Effective() map[string]string {
+	p := processorPrefix
+	itoa := strconv.Itoa
+	btoa := strconv.FormatBool
+	out := map[string]string{
+		p + "file_prefix":              c.FilePrefix,
+		p + "timezone":                 c.Upload.Location.String(),
+		p + "log_level":                c.LogLevel,
+		p + "rotate_interval_sec":      itoa(c.RotateIntervalSec),
+		p + "rotate_size_mb":           itoa(c.RotateSizeMB),
+		p + "upload_interval_sec":      itoa(c.UploadIntervalSec),
+		p + "upload_policy":            c.Upload.Policy,
+		p + "upload_path_template":     c.Upload.PathTemplate,
+		p + "upload_rate_limit_kbps":   itoa(c.Upload.RateLimitKBps),
+		p + "upload_lease_sec":         itoa(c.Upload.LeaseSec),
+		p + "upload_temp_max_age_sec":  itoa(c.Upload.TempMaxAgeSec),
+		p + "upload_retention_days":    itoa(c.Upload.Retention.Days),
+		p + "upload_retention_max_mb":  itoa(c.Upload.Retention.MaxMB),
+		p + "upload_retention_dry_run": btoa(c.Upload.Retention.DryRun),
+		p + "instance_id":              c.Upload.InstanceID,
+		p + "debug_print_interval":     itoa(c.DebugPrintInterval),
+		p + "ingest_chan_capacity":     itoa(c.IngestChanCapacity),
+		p + "ingest_chan_timeout_ms":   itoa(c.IngestChanTimeoutMs),
+		p + "metrics_listen":           c.MetricsListen,
+		p + "diag_enabled":             btoa(c.Diag.Enabled),
+		p + "diag_interval_sec":        itoa(c.Diag.IntervalSec),
+		p + "admin_enabled":            btoa(c.Admin.Enabled),
+		p + "status_report_enabled":    btoa(c.StatusReport.Enabled),
+		p + "otlp_endpoint":            c.OTLP.Endpoint,
+	}
+
+	if c.StructuredFile != "" {
+		out[configFileKey] = c.StructuredFile
+	}
+
+	windows := make([]string, 0, len(c.Upload.Windows))
+	for _, w := range c.Upload.Windows {
+		windows = append(windows, w.String())
+	}
+	out[p+"upload_windows"] = strings.Join(windows, ",")
+
+	names := make([]string, 0, len(c.Upload.Targets))
+	for _, t := range c.Upload.Targets {
+		names = append(names, t.Name)
+		tp := p + "upload_target_" + t.Name + "_"
+		out[tp+"host"] = t.Host
+		out[tp+"port"] = itoa(t.Port)
+		out[tp+"user"] = t.User
+		out[tp+"pass"] = RedactValue(t.Pass)
+		out[tp+"dir"] = t.Dir
+		out[tp+"timeout"] = itoa(t.TimeoutSec)
+	}
+	out[p+"upload_targets"] = strings.Join(names, ",")
+
+	pipes := make([]string, 0, len(c.Pipelines))
+	for _, pc := range c.Pipelines {
+		pipes = append(pipes, pc.Name)
+		pp := p + "pipeline_" + pc.Name + "_"
+		out[pp+"input"] = pc.Input
+		out[pp+"schema"] = pc.Schema
+		out[pp+"file_prefix"] = pc.FilePrefix
+		out[pp+"rotate_interval_sec"] = itoa(pc.RotateIntervalSec)
+		out[pp+"rotate_size_mb"] = itoa(pc.RotateSizeMB)
+		out[pp+"upload_interval_sec"] = itoa(pc.UploadIntervalSec)
+		out[pp+"upload_path_template"] = pc.Upload.PathTemplate
+		if len(pc.Columns) > 0 {
+			out[pp+"columns"] = formatColumns(pc.Columns)
+		}
+		if len(pc.Upload.Targets) > 0 {
+			out[pp+"ftp_dir"] = pc.Upload.Targets[0].Dir
+		}
+	}
+	if len(pipes) > 0 {
+		out[p+"pipelines"] = strings.Join(pipes, ",")
+	}
+
+	if c.Admin.Enabled {
+		out[p+"admin_listen"] = c.Admin.Listen
+		out[p+"admin_token"] = RedactValue(c.Admin.Token)
+		out[p+"admin_input_stale_sec"] = itoa(c.Admin.InputStaleSec)
+	}
+	if sr := c.StatusReport; sr.Enabled {
+		sp := p + "status_report_"
+		out[sp+"url"] = sr.URL
+		out[sp+"uuid"] = sr.UUID
+		out[sp+"interval_sec"] = itoa(sr.IntervalSec)
+		out[sp+"file_path"] = sr.FilePath
+		out[sp+"file_max_mb"] = itoa(sr.FileMaxMB)
+		out[sp+"file_backups"] = itoa(sr.FileBackups)
+		out[sp+"queue_max_mb"] = itoa(sr.QueueMaxMB)
+		out[sp+"queue_max_age_sec"] = itoa(sr.QueueMaxAgeSec)
+		out[sp+"schema_version"] = itoa(sr.SchemaVersion)
+		out[sp+"token_file"] = sr.TokenFile
+		out[sp+"hmac_key_file"] = sr.HMACKeyFile
+		out[sp+"tls_cert_file"] = sr.TLSCertFile
+		out[sp+"tls_key_file"] = sr.TLSKeyFile
+		out[sp+"tls_ca_file"] = sr.TLSCAFile
+		out[sp+"commands_enabled"] = btoa(sr.CommandsEnabled)
+		if sr.CommandsEnabled {
+			out[sp+"command_key_file"] = sr.CommandKeyFile
+			out[sp+"command_max_age_sec"] = itoa(sr.CommandMaxAgeSec)
+			out[sp+"audit_log"] = sr.AuditLogPath
+		}
+	}
+	if o := c.OTLP; o.Endpoint != "" {
+		headers := make([]string, 0, len(o.Headers))
+		for k := range o.Headers {
+			headers = append(headers, k+"="+redactedValue)
+		}
+		sort.Strings(headers)
+		out[p+"otlp_headers"] = strings.Join(headers, ",")
+		out[p+"otlp_service_name"] = o.ServiceName
+		out[p+"otlp_interval_sec"] = itoa(o.IntervalSec)
+		out[p+"otlp_timeout_sec"] = itoa(o.TimeoutSec)
+		out[p+"otlp_metrics_enabled"] = btoa(o.MetricsEnabled)
+		out[p+"otlp_traces_enabled"] = btoa(o.TracesEnabled)
+	}
+	if rc := c.RemoteConfig; rc.URL != "" {
+		out[p+"remote_config_url"] = rc.URL
+		out[p+"remote_config_interval_sec"] = itoa(rc.IntervalSec)
+		out[p+"remote_config_timeout_sec"] = itoa(rc.TimeoutSec)
+		out[p+"remote_config_token_file"] = rc.TokenFile
+		out[p+"remote_config_signing_key_file"] = rc.SigningKeyFile
+	}
+	if g := c.GeoIP; g.Enabled() {
+		out[p+"geoip_city_db"] = g.CityDB
+		out[p+"geoip_asn_db"] = g.ASNDB
+		out[p+"geoip_fields"] = strings.Join(g.Fields, ",")
+		out[p+"geoip_language"] = g.Language
+		out[p+"geoip_cache_size"] = itoa(g.CacheSize)
+		out[p+"geoip_check_interval_sec"] = itoa(g.CheckIntervalSec)
+	}
+	if z := c.Zoning; z.Enabled() {
+		names := make([]string, 0, len(z.Zones))
+		for _, zone := range z.Zones {
+			names = append(names, zone.Name)
+			zp := p + "zone_" + zone.Name + "_"
+			prefixes := make([]string, 0, len(zone.Prefixes))
+			for _, pfx := range zone.Prefixes {
+				prefixes = append(prefixes, pfx.String())
+			}
+			out[zp+"prefixes"] = strings.Join(prefixes, ",")
+			labels := make([]string, 0, len(zone.Labels))
+			for k, v := range zone.Labels {
+				labels = append(labels, k+"="+v)
+			}
+			sort.Strings(labels)
+			out[zp+"labels"] = strings.Join(labels, ",")
+		}
+		out[p+"zones"] = strings.Join(names, ",")
+		out[p+"zone_labels"] = strings.Join(z.LabelKeys, ",")
+	}
+	out[p+"sampling_rate"] = itoa(c.Sampling.Rate)
+	out[p+"sampling_per_exporter"] = btoa(c.Sampling.PerExporter)
+	out[p+"anonymize_mode"] = c.Anonymize.Mode
+	if a := c.Anonymize; a.Enabled() {
+		switch a.Mode {
+		case AnonymizeCryptoPAn:
+			out[p+"anonymize_key_file"] = a.KeyFile
+		case AnonymizeTruncate:
+			out[p+"anonymize_ipv4_prefix"] = itoa(a.IPv4Prefix)
+			out[p+"anonymize_ipv6_prefix"] = itoa(a.IPv6Prefix)
+		}
+		exempt := make([]string, 0, len(a.Exempt))
+		for _, pfx := range a.Exempt {
+			exempt = append(exempt, pfx.String())
+		}
+		out[p+"anonymize_exempt"] = strings.Join(exempt, ",")
+	}
+	if len(c.OutputColumns) > 0 {
+		out[p+"output_columns"] = formatColumns(c.OutputColumns)
+	}
+	out[p+"service_columns"] = btoa(c.Services.Columns)
+	if c.Services.File != "" {
+		out[p+"service_file"] = c.Services.File
+	}
+	if len(c.Services.Overrides) > 0 {
+		out[p+"service_overrides"] = strings.Join(c.Services.Overrides, ",")
+	}
+	out[p+"filter_mode"] = c.Filter.Mode
+	if f := c.Filter; f.Enabled() {
+		names := make([]string, 0, len(f.Rules))
+		for _, r := range f.Rules {
+			names = append(names, r.Name)
+			out[p+"filter_"+r.Name+"_expr"] = r.Expr
+		}
+		out[p+"filters"] = strings.Join(names, ",")
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 输出列的派生函数及其参数个数
+const (
+	ColumnFuncDuration  = "duration"   // TIMESTAMP_MAX - TIMESTAMP_MIN（秒）
+	ColumnFuncISO       = "iso"        // Unix 秒 -> RFC 3339（按 processor_timezone）
+	ColumnFuncTCPFlags  = "tcp_flags"  // TCP 标志位 -> SYN|ACK
+	ColumnFuncProtoName = "proto_name" // 协议号 -> tcp/udp/...
+)
+
+var columnFuncArgs = map[string]int{
+	ColumnFuncDuration:  0,
+	ColumnFuncISO:       1,
+	ColumnFuncTCPFlags:  1,
+	ColumnFuncProtoName: 1,
+}
+
+var (
+	columnNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	columnFuncRe = regexp.MustCompile(`^([a-z_]+)\(\s*([A-Za-z0-9_]*)\s*\)$`)
+)
+
+// OutputColumn 输出文件中的一列：直接取某一源列，或由派生函数计算
+type OutputColumn struct {
+	Name   string // 输出列名（写入 manifest）
+	Source string // 源列名（大小写不敏感，duration 为空）
+	Func   string // 派生函数，为空表示原样取源列
+}
+
+// String 还原为配置写法（name=expr）
+func (c OutputColumn) String() string {
+	expr := c.Source
+	if c.Func != "" {
+		expr = c.Func + "(" + c.Source + ")"
+	}
+	if c.Name == expr || (c.Source == "" && c.Name == c.Func) {
+		return expr
+	}
+	return c.Name + "=" + expr
+}
+
+// parseColumns 解析逗号分隔的输出列：[name=]column 或 [name=]func(column)，
+// 列的先后即输出顺序，未列出的列不输出；源列是否存在在管道启动时按实际输出列校验
+func parseColumns(key, v string) ([]OutputColumn, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	var out []OutputColumn
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var col OutputColumn
+		name, expr, renamed := strings.Cut(item, "=")
+		if !renamed {
+			expr = name
+		}
+		name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+		if m := columnFuncRe.FindStringSubmatch(expr); m != nil {
+			args, ok := columnFuncArgs[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("%s: 未知的函数 %s", key, m[1])
+			}
+			if (m[2] != "") != (args == 1) {
+				return nil, fmt.Errorf("%s: %s 需要 %d 个参数", key, m[1], args)
+			}
+			col.Func, col.Source = m[1], m[2]
+			if !renamed {
+				// 未重命名时：duration() -> duration，iso(TIMESTAMP_MIN) -> timestamp_min_iso
+				name = col.Func
+				if col.Source != "" {
+					name = strings.ToLower(col.Source) + "_" + col.Func
+				}
+			}
+		} else {
+			if !columnNameRe.MatchString(expr) {
+				return nil, fmt.Errorf("%s: 无效的列 %q", key, item)
+			}
+			col.Source = expr
+		}
+		if !columnNameRe.MatchString(name) {
+			return nil, fmt.Errorf("%s: 无效的列名 %q", key, name)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%s: 输出列名重复: %s", key, name)
+		}
+		seen[strings.ToLower(name)] = true
+		col.Name = name
+		out = append(out, col)
+	}
+	return out, nil
+}
+
+// formatColumns 输出列还原为配置写法（用于 Effective）
+func formatColumns(cols []OutputColumn) string {
+	items := make([]string, 0, len(cols))
+	for _, c := range cols {
+		items = append(items, c.String())
+	}
+	return strings.Join(items, ",")
+}
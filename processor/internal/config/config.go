@@ -2,33 +2,87 @@ package config
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const processorPrefix = "processor_"
 
 // ProcessorConfig 包含 processor 的所有配置项
-// 配置来源：pmacct.conf 中以 processor_ 开头的 key: value 行（可注释）
+// 配置来源：pmacct.conf 中以 processor_ 开头的 key: value 行（可注释），
+// 以及 YAML/TOML 结构化配置文件（展开为同名扁平键，见 structured.go）
 type ProcessorConfig struct {
 	FTPHost              string
 	FTPPort              int
 	FTPUser              string
 	FTPPass              string
 	FTPDir               string
-	FTPOptions           FTPOptions // FTP选项配置
-	Diag                 DiagConfig // 诊断采集配置
+	FTPOptions           FTPOptions   // FTP选项配置
+	Upload               UploadConfig // 上传目标与策略配置
+	Diag                 DiagConfig   // 诊断采集配置
 	RotateIntervalSec    int
 	RotateSizeMB         int
 	FilePrefix           string
 	UploadIntervalSec    int
-	DebugPrintInterval   int // 调试打印间隔（行数），默认为0（不打印）
-	DebugPrintStartLines int // 调试打印开始行数，前N行会打印，默认为0（不打印）
-	IngestChanCapacity   int // stdin -> writer 通道容量（行数）
-	IngestChanTimeoutMs  int // 通道写入超时（毫秒），超时则丢弃该行
+	Timezone             string // 时区（用于上传时间窗等），默认使用本地时区
+	DebugPrintInterval   int    // 调试打印间隔（行数），默认为0（不打印）
+	DebugPrintStartLines int    // 调试打印开始行数，前N行会打印，默认为0（不打印）
+	IngestChanCapacity   int    // stdin -> writer 通道容量（行数）
+	IngestChanTimeoutMs  int    // 通道写入超时（毫秒），超时则丢弃该行
 	StatusReport         StatusReportConfig
+	MetricsListen        string // Prometheus /metrics 监听地址（如 127.0.0.1:9464），为空则不启用
+	Admin                AdminConfig
+	OTLP                 OTLPConfig
+	RemoteConfig         RemoteConfig
+	Pipelines            []PipelineConfig  // 除默认管道（stdin）外的命名管道
+	GeoIP                GeoIPConfig       // 校验后追加 GeoIP/ASN 列
+	Zoning               ZoningConfig      // 按本地前缀追加区域、方向与标签列
+	Anonymize            AnonymizeConfig   // 写入前对地址做匿名化
+	Sampling             SamplingConfig    // 按采样率放大 PACKETS/BYTES
+	Filter               FilterConfig      // 按表达式丢弃或保留流
+	Services             ServiceConfig     // 按协议与端口识别服务
+	OutputColumns        []OutputColumn    // 默认管道（及未单独配置的命名管道）的输出列，为空时输出全部列
+	LogLevel             string            // 日志级别（debug|info|warn|error），为空时使用 -log-level 参数
+	ConfigHash           string            // 配置文件内容与环境变量覆盖的 SHA-256（十六进制），用于上报与排查配置漂移
+	Values               map[string]string // 生效的全部 processor_* 键值（已应用环境变量覆盖与引用解析）
+	EnvOverrides         []string          // 被 PROCESSOR_* 环境变量覆盖的配置键
+	ResolvedRefs         []string          // 取值来自 env:/file: 引用的配置键
+	StructuredFile       string            // 使用的 YAML/TOML 配置文件（未使用时为空）
+	ShadowedKeys         []string          // 结构化配置文件中被 pmacct.conf 同名 processor_* 覆盖的键
+	RemoteKeys           []string          // 取值来自远程配置的键
+}
+
+// AdminConfig 本地管理接口配置
+type AdminConfig struct {
+	Enabled       bool
+	Listen        string // 监听地址，默认仅本机 127.0.0.1:9465
+	Token         string // 非空时除探针外的接口需携带该 token
+	InputStaleSec int    // 超过该时长未收到输入则 /readyz 判定为未就绪
+}
+
+// OTLPConfig OpenTelemetry OTLP/HTTP 导出配置（Endpoint 为空则不启用）
+type OTLPConfig struct {
+	Endpoint       string            // 接收端基础地址（如 http://127.0.0.1:4318），自动拼接 /v1/metrics 与 /v1/traces
+	Headers        map[string]string // 附加请求头（如认证）
+	ServiceName    string
+	IntervalSec    int // 指标导出间隔（秒）
+	TimeoutSec     int
+	MetricsEnabled bool
+	TracesEnabled  bool
+}
+
+// RemoteConfig 远程配置拉取（URL 为空则不启用）
+type RemoteConfig struct {
+	URL            string // 返回 processor_* 配置的 HTTP(S) 地址
+	IntervalSec    int    // 轮询间隔（秒）
+	TimeoutSec     int
+	TokenFile      string // Bearer token 文件
+	SigningKeyFile string // HMAC-SHA256 签名校验密钥文件，配置后未签名或签名错误的配置一律拒绝
 }
 
 // FTPOptions FTP选项配置
@@ -36,6 +90,72 @@ type FTPOptions struct {
 	TimeoutSec int // FTP操作超时时间（秒）
 }
 
+// 上传策略
+const (
+	UploadPolicyFailover  = "failover"  // 按顺序尝试目标，任一成功即视为送达
+	UploadPolicyReplicate = "replicate" // 所有目标都送达后才删除本地文件
+)
+
+// UploadConfig 上传配置（多目标 + 策略）
+type UploadConfig struct {
+	Targets       []FTPTarget
+	Policy        string
+	PathTemplate  string         // 远端子目录模板，如 {type}/{host}/{yyyy}/{mm}/{dd}/{HH}；为空则直接放在目标目录
+	InstanceID    string         // 实例标识，用于远端临时文件名与租约（默认本机 FQDN）
+	TempMaxAgeSec int            // 本实例远端临时文件超过该时长才会被清理
+	LeaseSec      int            // 远端租约有效期（秒），超时后其他实例可接管同名文件
+	RateLimitKBps int            // 上传限速（KB/s，所有管道与目标共享），0 表示不限速
+	Windows       []TimeWindow   // 允许上传的时间窗，为空表示不限制
+	Location      *time.Location // 时间窗所用时区（来自 processor_timezone）
+	Retention     RetentionConfig
+}
+
+// RetentionConfig 远端保留策略（仅清理本实例记录为已上传的文件）
+type RetentionConfig struct {
+	Days   int  // 超过 N 天的远端文件删除，0 表示不按时间清理
+	MaxMB  int  // 每个目标的远端容量预算（MB），超出时从最旧的开始删除，0 表示不限制
+	DryRun bool // 只记录日志，不实际删除
+}
+
+// Enabled 是否启用远端保留清理
+func (r RetentionConfig) Enabled() bool {
+	return r.Days > 0 || r.MaxMB > 0
+}
+
+// TimeWindow 一天内的时间窗（分钟），End < Start 表示跨零点，如 22:00-06:00
+type TimeWindow struct {
+	StartMin int
+	EndMin   int
+}
+
+// Contains 判断时间是否落在时间窗内
+func (w TimeWindow) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.StartMin <= w.EndMin {
+		return m >= w.StartMin && m < w.EndMin
+	}
+	return m >= w.StartMin || m < w.EndMin
+}
+
+// String 以 HH:MM-HH:MM 格式输出
+func (w TimeWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.StartMin/60, w.StartMin%60, w.EndMin/60, w.EndMin%60)
+}
+
+// RemotePathPlaceholders 远端路径模板支持的占位符
+var RemotePathPlaceholders = []string{"{type}", "{host}", "{yyyy}", "{mm}", "{dd}", "{HH}"}
+
+// FTPTarget 单个 FTP 上传目标
+type FTPTarget struct {
+	Name       string
+	Host       string
+	Port       int
+	User       string
+	Pass       string
+	Dir        string
+	TimeoutSec int
+}
+
 // DiagConfig 诊断采集配置（宿主机日志 + 容器进程日志）
 type DiagConfig struct {
 	Enabled     bool
@@ -44,15 +164,37 @@ type DiagConfig struct {
 
 // StatusReportConfig 状态上报配置
 type StatusReportConfig struct {
-	Enabled     bool
-	URL         string
-	IntervalSec int
-	UUID        string
-	FilePath    string
-	FileMaxMB   int
-	FileBackups int
+	Enabled        bool
+	URL            string
+	IntervalSec    int
+	UUID           string
+	FilePath       string
+	FileMaxMB      int
+	FileBackups    int
+	QueueMaxMB     int // 上报失败时本地队列容量上限（MB）
+	QueueMaxAgeSec int // 队列中报告的最长保留时间（秒）
+	SchemaVersion  int // 上报报文版本：1 为旧版扁平字段，2 为带管道健康信息的结构化报文
+
+	// 认证凭据均从文件读取，避免明文写入 pmacct.conf
+	TokenFile   string // Bearer token 文件
+	HMACKeyFile string // HMAC-SHA256 签名密钥文件
+	TLSCertFile string // 客户端证书（mTLS）
+	TLSKeyFile  string // 客户端私钥（mTLS）
+	TLSCAFile   string // 校验服务端证书的 CA 证书包
+
+	// 服务端通过上报响应下发的签名指令
+	CommandsEnabled  bool
+	CommandKeyFile   string // 指令签名校验密钥文件，未配置时使用 HMACKeyFile
+	CommandMaxAgeSec int    // 指令签发时间与本地时间的最大偏差（秒）
+	AuditLogPath     string // 指令审计日志路径，默认 <data_dir>/statusreport/audit.log
 }
 
+// 状态上报报文版本
+const (
+	StatusSchemaV1 = 1
+	StatusSchemaV2 = 2
+)
+
 // parseProcessorConfig 解析 pmacct.conf 中的 processor_* 配置行
 // 仅解析未注释行：
 // processor_foo: value
@@ -112,24 +254,57 @@ func parseBool(value string) (bool, error) {
 
 // LoadConfig 从 pmacct.conf 中解析 processor 配置项
 func LoadConfig(configPath string) (*ProcessorConfig, error) {
-	// 检查文件是否存在
-	if _, err := os.Stat(configPath); err != nil {
-		return nil, fmt.Errorf("配置文件不存在: %w", err)
-	}
+	return LoadConfigWithOverlay(configPath, nil)
+}
 
-	fileContent, err := os.ReadFile(configPath)
+// LoadConfigWithOverlay 解析本地配置并叠加远程下发的配置（overlay 为 nil 时等同 LoadConfig）
+func LoadConfigWithOverlay(configPath string, overlay *Overlay) (*ProcessorConfig, error) {
+	src, err := readSources(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		return nil, err
+	}
+	if err := src.applyOverlay(overlay); err != nil {
+		return nil, err
 	}
 
-	kv := parseProcessorConfig(string(fileContent))
+	kv := src.kv
+	overrides := applyEnvOverrides(kv, os.Environ())
 	if len(kv) == 0 {
-		return nil, fmt.Errorf("未找到 processor_* 配置项，请在 pmacct.conf 中添加 processor_ 开头的 key: value")
+		return nil, fmt.Errorf("未找到 processor_* 配置项，请在 pmacct.conf 中添加 processor_ 开头的 key: value，或使用 YAML/TOML 配置文件")
+	}
+
+	// 哈希覆盖文件内容与环境变量覆盖（引用解析前），引用的密钥本身不参与
+	h := sha256.New()
+	for _, c := range src.contents {
+		h.Write(c)
+	}
+	for _, k := range overrides {
+		fmt.Fprintf(h, "\n%s=%s", k, kv[k])
+	}
+
+	// 远程下发的取值不解析引用；被环境变量覆盖的键以本地取值为准，照常解析
+	untrusted := make(map[string]bool, len(src.remote))
+	for _, k := range src.remote {
+		untrusted[k] = true
+	}
+	for _, k := range overrides {
+		delete(untrusted, k)
+	}
+	refs, err := resolveRefs(kv, untrusted)
+	if err != nil {
+		return nil, err
 	}
 
 	cfg := &ProcessorConfig{
 		IngestChanCapacity:  -1,
 		IngestChanTimeoutMs: -1,
+		ConfigHash:          hex.EncodeToString(h.Sum(nil)),
+		Values:              kv,
+		EnvOverrides:        overrides,
+		ResolvedRefs:        refs,
+		StructuredFile:      src.structured,
+		ShadowedKeys:        src.shadowed,
+		RemoteKeys:          src.remote,
 	}
 
 	cfg.FTPHost = kv[processorPrefix+"ftp_host"]
@@ -137,9 +312,19 @@ func LoadConfig(configPath string) (*ProcessorConfig, error) {
 	cfg.FTPPass = kv[processorPrefix+"ftp_pass"]
 	cfg.FTPDir = kv[processorPrefix+"ftp_dir"]
 	cfg.FilePrefix = kv[processorPrefix+"file_prefix"]
+	cfg.Timezone = kv[processorPrefix+"timezone"]
+	cfg.MetricsListen = kv[processorPrefix+"metrics_listen"]
+	cfg.LogLevel = strings.ToLower(kv[processorPrefix+"log_level"])
 	cfg.StatusReport.URL = kv[processorPrefix+"status_report_url"]
 	cfg.StatusReport.UUID = kv[processorPrefix+"status_report_uuid"]
 	cfg.StatusReport.FilePath = kv[processorPrefix+"status_report_file_path"]
+	cfg.StatusReport.TokenFile = kv[processorPrefix+"status_report_token_file"]
+	cfg.StatusReport.HMACKeyFile = kv[processorPrefix+"status_report_hmac_key_file"]
+	cfg.StatusReport.TLSCertFile = kv[processorPrefix+"status_report_tls_cert_file"]
+	cfg.StatusReport.TLSKeyFile = kv[processorPrefix+"status_report_tls_key_file"]
+	cfg.StatusReport.TLSCAFile = kv[processorPrefix+"status_report_tls_ca_file"]
+	cfg.StatusReport.CommandKeyFile = kv[processorPrefix+"status_report_command_key_file"]
+	cfg.StatusReport.AuditLogPath = kv[processorPrefix+"status_report_audit_log"]
 
 	if v, ok := kv[processorPrefix+"ftp_port"]; ok {
 		if num, err := strconv.Atoi(v); err != nil {
@@ -190,6 +375,34 @@ func LoadConfig(configPath string) (*ProcessorConfig, error) {
 			cfg.StatusReport.FileBackups = num
 		}
 	}
+	if v, ok := kv[processorPrefix+"status_report_queue_max_mb"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_status_report_queue_max_mb 不是整数: %w", err)
+		} else {
+			cfg.StatusReport.QueueMaxMB = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"status_report_schema_version"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_status_report_schema_version 不是整数: %w", err)
+		} else {
+			cfg.StatusReport.SchemaVersion = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"status_report_command_max_age_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_status_report_command_max_age_sec 不是整数: %w", err)
+		} else {
+			cfg.StatusReport.CommandMaxAgeSec = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"status_report_queue_max_age_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_status_report_queue_max_age_sec 不是整数: %w", err)
+		} else {
+			cfg.StatusReport.QueueMaxAgeSec = num
+		}
+	}
 	if v, ok := kv[processorPrefix+"diag_interval_sec"]; ok {
 		if num, err := strconv.Atoi(v); err != nil {
 			return nil, fmt.Errorf("processor_diag_interval_sec 不是整数: %w", err)
@@ -243,6 +456,187 @@ func LoadConfig(configPath string) (*ProcessorConfig, error) {
 		}
 		cfg.StatusReport.Enabled = b
 	}
+	if v, ok := kv[processorPrefix+"status_report_commands_enabled"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_status_report_commands_enabled 解析失败: %w", err)
+		}
+		cfg.StatusReport.CommandsEnabled = b
+	}
+
+	cfg.Admin.Listen = kv[processorPrefix+"admin_listen"]
+	cfg.Admin.Token = kv[processorPrefix+"admin_token"]
+	if v, ok := kv[processorPrefix+"admin_enabled"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_admin_enabled 解析失败: %w", err)
+		}
+		cfg.Admin.Enabled = b
+	}
+	if v, ok := kv[processorPrefix+"admin_input_stale_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_admin_input_stale_sec 不是整数: %w", err)
+		} else {
+			cfg.Admin.InputStaleSec = num
+		}
+	}
+
+	// 解析 OTLP 导出配置
+	cfg.OTLP.Endpoint = strings.TrimRight(kv[processorPrefix+"otlp_endpoint"], "/")
+	cfg.OTLP.ServiceName = kv[processorPrefix+"otlp_service_name"]
+	cfg.OTLP.MetricsEnabled = true
+	cfg.OTLP.TracesEnabled = true
+	if v, ok := kv[processorPrefix+"otlp_headers"]; ok {
+		headers, err := parseHeaderList(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_otlp_headers 解析失败: %w", err)
+		}
+		cfg.OTLP.Headers = headers
+	}
+	if v, ok := kv[processorPrefix+"otlp_interval_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_otlp_interval_sec 不是整数: %w", err)
+		} else {
+			cfg.OTLP.IntervalSec = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"otlp_timeout_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_otlp_timeout_sec 不是整数: %w", err)
+		} else {
+			cfg.OTLP.TimeoutSec = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"otlp_metrics_enabled"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_otlp_metrics_enabled 解析失败: %w", err)
+		}
+		cfg.OTLP.MetricsEnabled = b
+	}
+	if v, ok := kv[processorPrefix+"otlp_traces_enabled"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_otlp_traces_enabled 解析失败: %w", err)
+		}
+		cfg.OTLP.TracesEnabled = b
+	}
+
+	// 解析远程配置拉取
+	cfg.RemoteConfig.URL = kv[processorPrefix+"remote_config_url"]
+	cfg.RemoteConfig.TokenFile = kv[processorPrefix+"remote_config_token_file"]
+	cfg.RemoteConfig.SigningKeyFile = kv[processorPrefix+"remote_config_signing_key_file"]
+	if v, ok := kv[processorPrefix+"remote_config_interval_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_remote_config_interval_sec 不是整数: %w", err)
+		} else {
+			cfg.RemoteConfig.IntervalSec = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"remote_config_timeout_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_remote_config_timeout_sec 不是整数: %w", err)
+		} else {
+			cfg.RemoteConfig.TimeoutSec = num
+		}
+	}
+
+	// 解析上传目标配置
+	cfg.Upload.Policy = strings.ToLower(kv[processorPrefix+"upload_policy"])
+	cfg.Upload.PathTemplate = kv[processorPrefix+"upload_path_template"]
+	cfg.Upload.InstanceID = kv[processorPrefix+"instance_id"]
+	if v, ok := kv[processorPrefix+"upload_temp_max_age_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_upload_temp_max_age_sec 不是整数: %w", err)
+		} else {
+			cfg.Upload.TempMaxAgeSec = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"upload_lease_sec"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_upload_lease_sec 不是整数: %w", err)
+		} else {
+			cfg.Upload.LeaseSec = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"upload_rate_limit_kbps"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_upload_rate_limit_kbps 不是整数: %w", err)
+		} else {
+			cfg.Upload.RateLimitKBps = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"upload_retention_days"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_upload_retention_days 不是整数: %w", err)
+		} else {
+			cfg.Upload.Retention.Days = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"upload_retention_max_mb"]; ok {
+		if num, err := strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("processor_upload_retention_max_mb 不是整数: %w", err)
+		} else {
+			cfg.Upload.Retention.MaxMB = num
+		}
+	}
+	if v, ok := kv[processorPrefix+"upload_retention_dry_run"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_upload_retention_dry_run 解析失败: %w", err)
+		}
+		cfg.Upload.Retention.DryRun = b
+	}
+	if v, ok := kv[processorPrefix+"upload_windows"]; ok {
+		windows, err := parseTimeWindows(v)
+		if err != nil {
+			return nil, fmt.Errorf("processor_upload_windows 解析失败: %w", err)
+		}
+		cfg.Upload.Windows = windows
+	}
+	targets, err := parseUploadTargets(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Upload.Targets = targets
+	pipelines, err := parsePipelines(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Pipelines = pipelines
+	geoip, err := parseGeoIP(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.GeoIP = geoip
+	zoning, err := parseZones(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Zoning = zoning
+	anonymize, err := parseAnonymize(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Anonymize = anonymize
+	sampling, err := parseSampling(kv, src.pmacct)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Sampling = sampling
+	filters, err := parseFilters(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Filter = filters
+	services, err := parseServices(kv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Services = services
+	if cfg.OutputColumns, err = parseColumns(processorPrefix+"output_columns", kv[processorPrefix+"output_columns"]); err != nil {
+		return nil, err
+	}
 
 	// 验证配置
 	if err := validateConfig(cfg); err != nil {
@@ -252,16 +646,87 @@ func LoadConfig(configPath string) (*ProcessorConfig, error) {
 	return cfg, nil
 }
 
-// validateConfig 验证配置的有效性
-func validateConfig(cfg *ProcessorConfig) error {
-	if cfg.FTPHost == "" {
-		return fmt.Errorf("processor_ftp_host 不能为空")
+// parseUploadTargets 解析 processor_upload_targets 列出的多个上传目标
+// 每个目标使用 processor_upload_target_<name>_<field> 配置，未配置的字段回退到 processor_ftp_*
+func parseUploadTargets(kv map[string]string) ([]FTPTarget, error) {
+	raw := kv[processorPrefix+"upload_targets"]
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var targets []FTPTarget
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("processor_upload_targets 中目标重复: %s", name)
+		}
+		seen[name] = true
+
+		prefix := processorPrefix + "upload_target_" + name + "_"
+		lookup := func(field string) (string, bool) {
+			if v, ok := kv[prefix+field]; ok {
+				return v, true
+			}
+			v, ok := kv[processorPrefix+"ftp_"+field]
+			return v, ok
+		}
+
+		t := FTPTarget{Name: name}
+		t.Host, _ = lookup("host")
+		t.User, _ = lookup("user")
+		t.Pass, _ = lookup("pass")
+		t.Dir, _ = lookup("dir")
+		if v, ok := lookup("port"); ok && v != "" {
+			num, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("上传目标 %s 的端口不是整数: %w", name, err)
+			}
+			t.Port = num
+		}
+		if v, ok := lookup("timeout"); ok && v != "" {
+			num, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("上传目标 %s 的超时不是整数: %w", name, err)
+			}
+			t.TimeoutSec = num
+		}
+		targets = append(targets, t)
 	}
-	if cfg.FTPUser == "" {
-		return fmt.Errorf("processor_ftp_user 不能为空")
+	return targets, nil
+}
+
+// parseHeaderList 解析 "k1=v1,k2=v2" 形式的请求头列表
+func parseHeaderList(v string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("无效的请求头 %q，应为 key=value", item)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(val)
 	}
-	if cfg.FTPPass == "" {
-		return fmt.Errorf("processor_ftp_pass 不能为空")
+	return headers, nil
+}
+
+// validateConfig 验证配置的有效性
+func validateConfig(cfg *ProcessorConfig) error {
+	if len(cfg.Upload.Targets) == 0 {
+		if cfg.FTPHost == "" {
+			return fmt.Errorf("processor_ftp_host 不能为空")
+		}
+		if cfg.FTPUser == "" {
+			return fmt.Errorf("processor_ftp_user 不能为空")
+		}
+		if cfg.FTPPass == "" {
+			return fmt.Errorf("processor_ftp_pass 不能为空")
+		}
 	}
 	if cfg.RotateIntervalSec < 1 {
 		return fmt.Errorf("processor_rotate_interval_sec 必须 >= 1")
@@ -288,6 +753,16 @@ func validateConfig(cfg *ProcessorConfig) error {
 	if cfg.Diag.IntervalSec <= 0 {
 		cfg.Diag.IntervalSec = cfg.UploadIntervalSec
 	}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("processor_timezone 无效: %w", err)
+		}
+		cfg.Upload.Location = loc
+	}
+	if err := validateUploadConfig(cfg); err != nil {
+		return err
+	}
 
 	// 设置调试打印间隔默认值
 	if cfg.DebugPrintInterval < 0 {
@@ -303,6 +778,47 @@ func validateConfig(cfg *ProcessorConfig) error {
 	} else if cfg.IngestChanTimeoutMs < 0 {
 		return fmt.Errorf("processor_ingest_chan_timeout_ms 必须 >= 0")
 	}
+	switch cfg.LogLevel {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("processor_log_level 仅支持 debug|info|warn|error: %s", cfg.LogLevel)
+	}
+	if cfg.OTLP.Endpoint != "" {
+		if !strings.HasPrefix(cfg.OTLP.Endpoint, "http://") && !strings.HasPrefix(cfg.OTLP.Endpoint, "https://") {
+			return fmt.Errorf("processor_otlp_endpoint 需以 http:// 或 https:// 开头: %s", cfg.OTLP.Endpoint)
+		}
+		if cfg.OTLP.ServiceName == "" {
+			cfg.OTLP.ServiceName = "processor"
+		}
+		if cfg.OTLP.IntervalSec <= 0 {
+			cfg.OTLP.IntervalSec = 60
+		}
+		if cfg.OTLP.TimeoutSec <= 0 {
+			cfg.OTLP.TimeoutSec = 10
+		}
+	}
+	if cfg.RemoteConfig.URL != "" {
+		if !strings.HasPrefix(cfg.RemoteConfig.URL, "http://") && !strings.HasPrefix(cfg.RemoteConfig.URL, "https://") {
+			return fmt.Errorf("processor_remote_config_url 需以 http:// 或 https:// 开头: %s", cfg.RemoteConfig.URL)
+		}
+		if cfg.RemoteConfig.SigningKeyFile == "" {
+			return fmt.Errorf("启用 processor_remote_config_url 时必须配置 processor_remote_config_signing_key_file")
+		}
+		if cfg.RemoteConfig.IntervalSec <= 0 {
+			cfg.RemoteConfig.IntervalSec = 300
+		}
+		if cfg.RemoteConfig.TimeoutSec <= 0 {
+			cfg.RemoteConfig.TimeoutSec = 10
+		}
+	}
+	if cfg.Admin.Enabled {
+		if cfg.Admin.Listen == "" {
+			cfg.Admin.Listen = "127.0.0.1:9465"
+		}
+		if cfg.Admin.InputStaleSec <= 0 {
+			cfg.Admin.InputStaleSec = 300
+		}
+	}
 	if cfg.StatusReport.Enabled {
 		if cfg.StatusReport.IntervalSec < 1 {
 			cfg.StatusReport.IntervalSec = 60
@@ -317,8 +833,205 @@ func validateConfig(cfg *ProcessorConfig) error {
 		if cfg.StatusReport.FileBackups < 0 {
 			cfg.StatusReport.FileBackups = 0
 		}
+		if cfg.StatusReport.QueueMaxMB <= 0 {
+			cfg.StatusReport.QueueMaxMB = 10
+		}
+		if cfg.StatusReport.QueueMaxAgeSec <= 0 {
+			cfg.StatusReport.QueueMaxAgeSec = 86400
+		}
+		switch cfg.StatusReport.SchemaVersion {
+		case 0:
+			cfg.StatusReport.SchemaVersion = StatusSchemaV2
+		case StatusSchemaV1, StatusSchemaV2:
+		default:
+			return fmt.Errorf("processor_status_report_schema_version 仅支持 1 或 2: %d", cfg.StatusReport.SchemaVersion)
+		}
+		if (cfg.StatusReport.TLSCertFile == "") != (cfg.StatusReport.TLSKeyFile == "") {
+			return fmt.Errorf("processor_status_report_tls_cert_file 与 processor_status_report_tls_key_file 需同时配置")
+		}
+		if cfg.StatusReport.CommandsEnabled {
+			if cfg.StatusReport.CommandKeyFile == "" {
+				cfg.StatusReport.CommandKeyFile = cfg.StatusReport.HMACKeyFile
+			}
+			if cfg.StatusReport.CommandKeyFile == "" {
+				return fmt.Errorf("启用 processor_status_report_commands_enabled 时需配置 processor_status_report_command_key_file 或 processor_status_report_hmac_key_file")
+			}
+			if cfg.StatusReport.CommandMaxAgeSec <= 0 {
+				cfg.StatusReport.CommandMaxAgeSec = 300
+			}
+		}
+	}
+	if err := validateGeoIP(&cfg.GeoIP); err != nil {
+		return err
+	}
+	if err := validateAnonymize(&cfg.Anonymize); err != nil {
+		return err
+	}
+	if err := validateSampling(&cfg.Sampling); err != nil {
+		return err
+	}
+	if err := validateFilters(&cfg.Filter); err != nil {
+		return err
+	}
+	if err := validateServices(&cfg.Services); err != nil {
+		return err
+	}
+	if err := validatePipelines(cfg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateUploadConfig 校验上传目标与策略；未配置多目标时由 processor_ftp_* 生成单一目标
+func validateUploadConfig(cfg *ProcessorConfig) error {
+	switch cfg.Upload.Policy {
+	case "":
+		cfg.Upload.Policy = UploadPolicyFailover
+	case UploadPolicyFailover, UploadPolicyReplicate:
+	default:
+		return fmt.Errorf("processor_upload_policy 仅支持 failover|replicate: %s", cfg.Upload.Policy)
+	}
+	if err := validatePathTemplate(cfg.Upload.PathTemplate); err != nil {
+		return err
+	}
+	if cfg.Upload.Retention.Days < 0 {
+		return fmt.Errorf("processor_upload_retention_days 必须 >= 0")
+	}
+	if cfg.Upload.Retention.MaxMB < 0 {
+		return fmt.Errorf("processor_upload_retention_max_mb 必须 >= 0")
+	}
+	if cfg.Upload.RateLimitKBps < 0 {
+		return fmt.Errorf("processor_upload_rate_limit_kbps 必须 >= 0")
+	}
+	if cfg.Upload.Location == nil {
+		cfg.Upload.Location = time.Local
+	}
+	if cfg.Upload.TempMaxAgeSec <= 0 {
+		cfg.Upload.TempMaxAgeSec = 3600
+	}
+	if cfg.Upload.LeaseSec <= 0 {
+		// 租约需覆盖单个文件的完整上传耗时
+		cfg.Upload.LeaseSec = 2 * cfg.FTPOptions.TimeoutSec
+		if cfg.Upload.LeaseSec < 600 {
+			cfg.Upload.LeaseSec = 600
+		}
+	}
+
+	if len(cfg.Upload.Targets) == 0 {
+		cfg.Upload.Targets = []FTPTarget{{
+			Name:       "default",
+			Host:       cfg.FTPHost,
+			Port:       cfg.FTPPort,
+			User:       cfg.FTPUser,
+			Pass:       cfg.FTPPass,
+			Dir:        cfg.FTPDir,
+			TimeoutSec: cfg.FTPOptions.TimeoutSec,
+		}}
+		return nil
+	}
+
+	for i := range cfg.Upload.Targets {
+		t := &cfg.Upload.Targets[i]
+		if t.Host == "" {
+			return fmt.Errorf("上传目标 %s 的 host 不能为空", t.Name)
+		}
+		if t.User == "" {
+			return fmt.Errorf("上传目标 %s 的 user 不能为空", t.Name)
+		}
+		if t.Pass == "" {
+			return fmt.Errorf("上传目标 %s 的 pass 不能为空", t.Name)
+		}
+		if t.Port == 0 {
+			t.Port = 21
+		}
+		if t.Dir == "" {
+			t.Dir = "/"
+		}
+		if t.TimeoutSec <= 0 {
+			t.TimeoutSec = cfg.FTPOptions.TimeoutSec
+		}
+	}
+	return nil
+}
+
+// parseTimeWindows 解析 "22:00-06:00, 12:00-13:00" 形式的时间窗列表
+func parseTimeWindows(value string) ([]TimeWindow, error) {
+	var windows []TimeWindow
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("时间窗格式应为 HH:MM-HH:MM: %s", part)
+		}
+		start, err := parseClock(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("时间窗起止相同: %s", part)
+		}
+		windows = append(windows, TimeWindow{StartMin: start, EndMin: end})
+	}
+	return windows, nil
+}
+
+// parseClock 解析 HH:MM（24:00 表示当天结束）
+func parseClock(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	hm := strings.SplitN(value, ":", 2)
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", value)
+	}
+	h, err := strconv.Atoi(hm[0])
+	if err != nil {
+		return 0, fmt.Errorf("小时不是整数: %s", value)
+	}
+	m, err := strconv.Atoi(hm[1])
+	if err != nil {
+		return 0, fmt.Errorf("分钟不是整数: %s", value)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("时间超出范围: %s", value)
 	}
+	return h*60 + m, nil
+}
 
+// validatePathTemplate 检查远端路径模板只包含已知占位符
+func validatePathTemplate(tmpl string) error {
+	rest := tmpl
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return fmt.Errorf("processor_upload_path_template 占位符未闭合: %s", tmpl)
+		}
+		ph := rest[start : start+end+1]
+		known := false
+		for _, p := range RemotePathPlaceholders {
+			if ph == p {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("processor_upload_path_template 含未知占位符 %s（支持 %s）", ph, strings.Join(RemotePathPlaceholders, " "))
+		}
+		rest = rest[start+end+1:]
+	}
+	if strings.Contains(tmpl, "..") {
+		return fmt.Errorf("processor_upload_path_template 不能包含 ..")
+	}
 	return nil
 }
 
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmacct/processor/internal/filter"
+)
+
+// FilterRule 一条命名的过滤规则
+type FilterRule struct {
+	Name string
+	Expr string
+}
+
+// FilterConfig 流过滤：drop 模式丢弃匹配任一规则的流，keep 模式仅保留匹配任一规则的流（未配置规则则不启用）
+type FilterConfig struct {
+	Mode  string
+	Rules []FilterRule
+}
+
+// Enabled 是否启用流过滤
+func (f FilterConfig) Enabled() bool {
+	return len(f.Rules) > 0
+}
+
+// parseFilters 解析 processor_filter_mode 与 processor_filters 列出的 processor_filter_<name>_expr
+func parseFilters(kv map[string]string) (FilterConfig, error) {
+	f := FilterConfig{Mode: strings.ToLower(strings.TrimSpace(kv[processorPrefix+"filter_mode"]))}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(kv[processorPrefix+"filters"], ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !pipelineNameRe.MatchString(name) {
+			return f, fmt.Errorf("processor_filters 中的规则名无效: %s", name)
+		}
+		if seen[name] {
+			return f, fmt.Errorf("processor_filters 中的规则名重复: %s", name)
+		}
+		seen[name] = true
+		key := processorPrefix + "filter_" + name + "_expr"
+		expr := strings.TrimSpace(kv[key])
+		if expr == "" {
+			return f, fmt.Errorf("过滤规则 %s 需配置 %s", name, key)
+		}
+		f.Rules = append(f.Rules, FilterRule{Name: name, Expr: expr})
+	}
+	return f, nil
+}
+
+// validateFilters 填充默认模式并编译全部表达式，使语法错误在启动或 check-config 时即报出
+func validateFilters(f *FilterConfig) error {
+	if f.Mode == "" {
+		f.Mode = filter.ModeDrop
+	}
+	if f.Mode != filter.ModeDrop && f.Mode != filter.ModeKeep {
+		return fmt.Errorf("processor_filter_mode 需为 %s 或 %s: %s", filter.ModeDrop, filter.ModeKeep, f.Mode)
+	}
+	for _, r := range f.Rules {
+		if _, err := filter.Compile(r.Name, r.Expr); err != nil {
+			return fmt.Errorf("processor_filter_%s_expr 编译失败: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// isFilterKey 是否为 processor_filter_<name>_expr 形式的配置项
+func isFilterKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, processorPrefix+"filter_")
+	if !ok {
+		return false
+	}
+	name, ok := strings.CutSuffix(rest, "_expr")
+	return ok && name != ""
+}
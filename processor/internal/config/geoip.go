@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GeoIP 可追加的字段（输出列为 src_<字段> 与 dst_<字段>）
+const (
+	GeoFieldCountry = "country" // ISO 3166 国家代码，来自 City/Country 库
+	GeoFieldCity    = "city"    // 城市名（processor_geoip_language 指定的语言），来自 City 库
+	GeoFieldASN     = "asn"     // 自治系统号，来自 ASN 库
+	GeoFieldASOrg   = "as_org"  // 自治系统组织名，来自 ASN 库
+)
+
+// GeoIPConfig 基于本地 MaxMind .mmdb 库的 GeoIP/ASN 富化（两个库都未配置则不启用）
+type GeoIPConfig struct {
+	CityDB           string   // GeoIP2/GeoLite2 City 或 Country 库
+	ASNDB            string   // GeoIP2/GeoLite2 ASN 库
+	Fields           []string // 追加的字段，默认按已配置的库取 country,city 与 asn,as_org
+	Language         string   // 城市名语言，默认 en
+	CacheSize        int      // 查询结果缓存条数（按 IP），0 表示不缓存
+	CheckIntervalSec int      // 检查库文件变化的间隔（秒），变化后自动重新加载
+}
+
+// Enabled 是否启用 GeoIP 富化
+func (g GeoIPConfig) Enabled() bool {
+	return g.CityDB != "" || g.ASNDB != ""
+}
+
+// parseGeoIP 解析 processor_geoip_* 配置（默认值在 validateGeoIP 中填充）
+func parseGeoIP(kv map[string]string) (GeoIPConfig, error) {
+	g := GeoIPConfig{
+		CityDB:    kv[processorPrefix+"geoip_city_db"],
+		ASNDB:     kv[processorPrefix+"geoip_asn_db"],
+		Language:  kv[processorPrefix+"geoip_language"],
+		CacheSize: -1,
+	}
+	if v := kv[processorPrefix+"geoip_fields"]; strings.TrimSpace(v) != "" {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+				g.Fields = append(g.Fields, f)
+			}
+		}
+	}
+	if v, ok := kv[processorPrefix+"geoip_cache_size"]; ok {
+		num, err := strconv.Atoi(v)
+		if err != nil {
+			return g, fmt.Errorf("processor_geoip_cache_size 不是整数: %w", err)
+		}
+		g.CacheSize = num
+	}
+	if v, ok := kv[processorPrefix+"geoip_check_interval_sec"]; ok {
+		num, err := strconv.Atoi(v)
+		if err != nil {
+			return g, fmt.Errorf("processor_geoip_check_interval_sec 不是整数: %w", err)
+		}
+		g.CheckIntervalSec = num
+	}
+	return g, nil
+}
+
+// validateGeoIP 校验字段与所需的库是否匹配并填充默认值
+func validateGeoIP(g *GeoIPConfig) error {
+	if !g.Enabled() {
+		return nil
+	}
+	if len(g.Fields) == 0 {
+		if g.CityDB != "" {
+			g.Fields = append(g.Fields, GeoFieldCountry, GeoFieldCity)
+		}
+		if g.ASNDB != "" {
+			g.Fields = append(g.Fields, GeoFieldASN, GeoFieldASOrg)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, f := range g.Fields {
+		if seen[f] {
+			return fmt.Errorf("processor_geoip_fields 字段重复: %s", f)
+		}
+		seen[f] = true
+		switch f {
+		case GeoFieldCountry, GeoFieldCity:
+			if g.CityDB == "" {
+				return fmt.Errorf("processor_geoip_fields 中的 %s 需要配置 processor_geoip_city_db", f)
+			}
+		case GeoFieldASN, GeoFieldASOrg:
+			if g.ASNDB == "" {
+				return fmt.Errorf("processor_geoip_fields 中的 %s 需要配置 processor_geoip_asn_db", f)
+			}
+		default:
+			return fmt.Errorf("processor_geoip_fields 不支持的字段: %s（可选 country,city,asn,as_org）", f)
+		}
+	}
+	if g.Language == "" {
+		g.Language = "en"
+	}
+	if g.CacheSize < 0 {
+		g.CacheSize = 100000
+	}
+	if g.CheckIntervalSec <= 0 {
+		g.CheckIntervalSec = 60
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// remoteConfigPrefix 远程配置拉取自身的配置项，只能在本地配置，远程下发时忽略
+const remoteConfigPrefix = processorPrefix + "remote_config_"
+
+// localOnlyKeys 指向本机路径或决定本机数据发往何处的配置项，只能在本地配置，远程下发时忽略；
+// 另外所有 *_file 键与管道的 input 同样只能在本地配置
+var localOnlyKeys = map[string]bool{
+	processorPrefix + "status_report_url":       true,
+	processorPrefix + "status_report_file_path": true,
+	processorPrefix + "status_report_audit_log": true,
+	processorPrefix + "geoip_city_db":           true,
+	processorPrefix + "geoip_asn_db":            true,
+}
+
+// isLocalOnlyKey 判断配置项是否只能在本地配置
+func isLocalOnlyKey(key string) bool {
+	switch {
+	case strings.HasPrefix(key, remoteConfigPrefix), localOnlyKeys[key], strings.HasSuffix(key, "_file"):
+		return true
+	case isPipelineKey(key) && strings.HasSuffix(key, "_input"):
+		return true
+	}
+	return false
+}
+
+// Overlay 远程下发的配置内容，叠加在本地配置之上
+type Overlay struct {
+	Content []byte
+	Format  string // 为空表示 processor_* 扁平行，或 yaml/toml
+}
+
+// FormatFromContentType 根据响应 Content-Type 判断配置格式（默认扁平行）
+func FormatFromContentType(contentType string) string {
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.Contains(ct, "yaml"):
+		return formatYAML
+	case strings.Contains(ct, "toml"):
+		return formatTOML
+	}
+	return ""
+}
+
+// ParseOverlay 解析远程配置内容，返回其中的 processor_* 键值（已剔除只能在本地配置的键）与被剔除的键
+func ParseOverlay(o *Overlay) (map[string]string, []string, error) {
+	var kv map[string]string
+	if o.Format == "" {
+		kv = parseProcessorConfig(string(o.Content))
+	} else {
+		var err error
+		if kv, err = parseStructuredConfig(o.Content, o.Format); err != nil {
+			return nil, nil, err
+		}
+	}
+	var ignored []string
+	for k := range kv {
+		if isLocalOnlyKey(k) {
+			ignored = append(ignored, k)
+			delete(kv, k)
+		}
+	}
+	sort.Strings(ignored)
+	return kv, ignored, nil
+}
+
+// applyOverlay 远程配置优先于本地文件（环境变量覆盖仍在其后应用）
+func (src *configSources) applyOverlay(o *Overlay) error {
+	if o == nil {
+		return nil
+	}
+	kv, ignored, err := ParseOverlay(o)
+	if err != nil {
+		return fmt.Errorf("解析远程配置失败: %w", err)
+	}
+	if len(ignored) > 0 {
+		slog.Warn("远程配置中的以下配置项只能在本地配置，已忽略", "keys", strings.Join(ignored, ","))
+	}
+	for k, v := range kv {
+		src.kv[k] = v
+		src.remote = append(src.remote, k)
+	}
+	sort.Strings(src.remote)
+	src.contents = append(src.contents, o.Content)
+	return nil
+}
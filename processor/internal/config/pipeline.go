@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultPipeline 默认管道名（读取 stdin，使用顶层配置）
+const DefaultPipeline = "default"
+
+// 管道输入类型
+const (
+	InputStdin = "stdin"
+	InputFIFO  = "fifo" // fifo:/path，命名管道，不存在时自动创建
+	InputUnix  = "unix" // unix:/path，Unix 域套接字，可接受多个连接
+	InputTCP   = "tcp"  // tcp:host:port
+)
+
+// SchemaPmacctCSV 当前唯一支持的输入格式：nfacctd print 插件输出的 11 列 CSV
+const SchemaPmacctCSV = "pmacct_csv"
+
+// pipelineFields processor_pipeline_<name>_<field> 支持的字段
+var pipelineFields = []string{
+	"input", "schema", "file_prefix", "rotate_interval_sec", "rotate_size_mb",
+	"upload_interval_sec", "ftp_dir", "upload_path_template", "columns",
+}
+
+var pipelineNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// PipelineConfig 一条命名管道：独立的输入、滚动写入与上传设置
+// 未配置的字段继承顶层配置，上传目标与策略与默认管道相同
+type PipelineConfig struct {
+	Name              string
+	Input             string // stdin | fifo:/path | unix:/path | tcp:host:port
+	Schema            string
+	FilePrefix        string
+	RotateIntervalSec int
+	RotateSizeMB      int
+	UploadIntervalSec int
+	Upload            UploadConfig
+	Columns           []OutputColumn // 输出列（未配置时继承 processor_output_columns，仍为空则输出全部列）
+}
+
+// InputKind 返回输入类型与地址（stdin 地址为空）
+func (p PipelineConfig) InputKind() (string, string) {
+	kind, addr, _ := strings.Cut(p.Input, ":")
+	return kind, addr
+}
+
+// DefaultPipeline 返回由顶层配置构成的默认管道
+func (c *ProcessorConfig) DefaultPipeline() PipelineConfig {
+	return PipelineConfig{
+		Name:              DefaultPipeline,
+		Input:             InputStdin,
+		Schema:            SchemaPmacctCSV,
+		FilePrefix:        c.FilePrefix,
+		RotateIntervalSec: c.RotateIntervalSec,
+		RotateSizeMB:      c.RotateSizeMB,
+		UploadIntervalSec: c.UploadIntervalSec,
+		Upload:            c.Upload,
+		Columns:           c.OutputColumns,
+	}
+}
+
+// parsePipelines 解析 processor_pipelines 列出的命名管道（仅读取原始取值，默认值在 validatePipelines 中继承）
+func parsePipelines(kv map[string]string) ([]PipelineConfig, error) {
+	raw := kv[processorPrefix+"pipelines"]
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var out []PipelineConfig
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !pipelineNameRe.MatchString(name) || name == DefaultPipeline {
+			return nil, fmt.Errorf("processor_pipelines 中的管道名无效: %s", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("processor_pipelines 中的管道名重复: %s", name)
+		}
+		seen[name] = true
+
+		prefix := processorPrefix + "pipeline_" + name + "_"
+		p := PipelineConfig{
+			Name:       name,
+			Input:      kv[prefix+"input"],
+			Schema:     strings.ToLower(kv[prefix+"schema"]),
+			FilePrefix: kv[prefix+"file_prefix"],
+		}
+		p.Upload.PathTemplate = kv[prefix+"upload_path_template"]
+		cols, err := parseColumns(prefix+"columns", kv[prefix+"columns"])
+		if err != nil {
+			return nil, err
+		}
+		p.Columns = cols
+		for field, dst := range map[string]*int{
+			"rotate_interval_sec": &p.RotateIntervalSec,
+			"rotate_size_mb":      &p.RotateSizeMB,
+			"upload_interval_sec": &p.UploadIntervalSec,
+		} {
+			if v, ok := kv[prefix+field]; ok && v != "" {
+				num, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("%s%s 不是整数: %w", prefix, field, err)
+				}
+				*dst = num
+			}
+		}
+		// ftp_dir 覆盖所有上传目标的目录，暂存在单一目标中，由 validatePipelines 展开
+		if dir := kv[prefix+"ftp_dir"]; dir != "" {
+			p.Upload.Targets = []FTPTarget{{Dir: dir}}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// validatePipelines 校验命名管道并继承顶层配置（需在顶层配置校验与默认值填充之后调用）
+func validatePipelines(cfg *ProcessorConfig) error {
+	inputs := make(map[string]string)
+	prefixes := map[string]string{cfg.FilePrefix: DefaultPipeline}
+	for i := range cfg.Pipelines {
+		p := &cfg.Pipelines[i]
+		name := processorPrefix + "pipeline_" + p.Name + "_"
+
+		kind, addr := p.InputKind()
+		switch kind {
+		case InputFIFO, InputUnix, InputTCP:
+			if addr == "" {
+				return fmt.Errorf("%sinput 缺少地址: %s", name, p.Input)
+			}
+		case InputStdin:
+			return fmt.Errorf("%sinput 不能为 stdin（stdin 由默认管道读取）", name)
+		case "":
+			return fmt.Errorf("%sinput 不能为空", name)
+		default:
+			return fmt.Errorf("%sinput 仅支持 fifo:/path|unix:/path|tcp:host:port: %s", name, p.Input)
+		}
+		if other, ok := inputs[p.Input]; ok {
+			return fmt.Errorf("管道 %s 与 %s 使用了相同的输入 %s", p.Name, other, p.Input)
+		}
+		inputs[p.Input] = p.Name
+
+		switch p.Schema {
+		case "":
+			p.Schema = SchemaPmacctCSV
+		case SchemaPmacctCSV:
+		default:
+			return fmt.Errorf("%sschema 仅支持 %s: %s", name, SchemaPmacctCSV, p.Schema)
+		}
+
+		if p.FilePrefix == "" {
+			p.FilePrefix = cfg.FilePrefix + p.Name + "_"
+		}
+		// 文件前缀需唯一，避免远端同目录下文件名冲突
+		if other, ok := prefixes[p.FilePrefix]; ok {
+			return fmt.Errorf("管道 %s 与 %s 使用了相同的 file_prefix %s", p.Name, other, p.FilePrefix)
+		}
+		prefixes[p.FilePrefix] = p.Name
+
+		if p.RotateIntervalSec == 0 {
+			p.RotateIntervalSec = cfg.RotateIntervalSec
+		}
+		if p.RotateSizeMB == 0 {
+			p.RotateSizeMB = cfg.RotateSizeMB
+		}
+		if p.UploadIntervalSec == 0 {
+			p.UploadIntervalSec = cfg.UploadIntervalSec
+		}
+		if len(p.Columns) == 0 {
+			p.Columns = cfg.OutputColumns
+		}
+		if p.RotateIntervalSec < 1 || p.RotateSizeMB < 1 || p.UploadIntervalSec < 1 {
+			return fmt.Errorf("管道 %s 的 rotate_interval_sec/rotate_size_mb/upload_interval_sec 必须 >= 1", p.Name)
+		}
+
+		dir := ""
+		if len(p.Upload.Targets) == 1 {
+			dir = p.Upload.Targets[0].Dir
+		}
+		tmpl := p.Upload.PathTemplate
+		if tmpl != "" {
+			if err := validatePathTemplate(tmpl); err != nil {
+				return fmt.Errorf("管道 %s: %w", p.Name, err)
+			}
+		}
+		p.Upload = cfg.Upload
+		p.Upload.Targets = append([]FTPTarget(nil), cfg.Upload.Targets...)
+		if dir != "" {
+			for j := range p.Upload.Targets {
+				p.Upload.Targets[j].Dir = dir
+			}
+		}
+		if tmpl != "" {
+			p.Upload.PathTemplate = tmpl
+		}
+	}
+	return nil
+}
+
+// isPipelineKey 判断是否为 processor_pipeline_<name>_<field> 形式的配置项
+func isPipelineKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, processorPrefix+"pipeline_")
+	if !ok {
+		return false
+	}
+	for _, f := range pipelineFields {
+		if name, ok := strings.CutSuffix(rest, "_"+f); ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 采样率来源
+const (
+	SamplingSourceProcessor = "processor_sampling_rate"
+	SamplingSourcePmacct    = "pmacct.conf sampling_rate"
+)
+
+// MaxSamplingRate 采样率上限（固定采样率与每条流的 SAMPLING_RATE 均适用）
+const MaxSamplingRate = 1 << 20
+
+// SamplingConfig 采样率校正：按采样率放大 PACKETS/BYTES 并追加 sampling_rate 列
+type SamplingConfig struct {
+	Rate         int    // 固定采样率，1 表示未采样
+	Source       string // Rate 的来源（未采样时为空）
+	PerExporter  bool   // 输入含 SAMPLING_RATE 列时按每条流（即每个 exporter 通告）的采样率校正，取值缺失时回退到 Rate
+	Renormalized bool   // pmacct.conf 开启了 nfacctd_renormalize，nfacctd 输出已放大
+}
+
+// Enabled 是否启用采样率校正
+func (s SamplingConfig) Enabled() bool {
+	return s.Rate > 1 || s.PerExporter
+}
+
+// parseSampling 解析 processor_sampling_*；processor_sampling_rate 为 auto（默认）时取 pmacct.conf 的 sampling_rate
+func parseSampling(kv, pmacct map[string]string) (SamplingConfig, error) {
+	s := SamplingConfig{Rate: 1}
+	if v, ok := kv[processorPrefix+"sampling_per_exporter"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("processor_sampling_per_exporter 解析失败: %w", err)
+		}
+		s.PerExporter = b
+	}
+	s.Renormalized = strings.EqualFold(strings.TrimSpace(pmacct["nfacctd_renormalize"]), "true")
+
+	v := strings.ToLower(strings.TrimSpace(kv[processorPrefix+"sampling_rate"]))
+	if v == "" || v == "auto" {
+		raw, ok := pmacct["sampling_rate"]
+		if !ok || s.Renormalized {
+			return s, nil
+		}
+		rate, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || rate < 1 || rate > MaxSamplingRate {
+			return s, fmt.Errorf("pmacct.conf 中的 sampling_rate 无效（需为 1~%d）: %q", MaxSamplingRate, raw)
+		}
+		if rate > 1 {
+			s.Rate, s.Source = rate, SamplingSourcePmacct
+		}
+		return s, nil
+	}
+	rate, err := strconv.Atoi(v)
+	if err != nil || rate < 1 || rate > MaxSamplingRate {
+		return s, fmt.Errorf("processor_sampling_rate 需为 auto 或 1~%d 的整数: %s", MaxSamplingRate, v)
+	}
+	if rate > 1 {
+		s.Rate, s.Source = rate, SamplingSourceProcessor
+	}
+	return s, nil
+}
+
+func validateSampling(s *SamplingConfig) error {
+	if s.Renormalized && s.Enabled() {
+		return fmt.Errorf("pmacct.conf 已开启 nfacctd_renormalize（nfacctd 输出已按采样率放大），不能再启用 processor 采样率校正")
+	}
+	return nil
+}
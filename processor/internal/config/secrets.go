@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envOverridePrefix 环境变量覆盖前缀：PROCESSOR_FTP_PASS 覆盖 processor_ftp_pass
+const envOverridePrefix = "PROCESSOR_"
+
+// reservedEnv 启动脚本自身使用的 PROCESSOR_* 变量，不作为配置覆盖
+var reservedEnv = map[string]bool{
+	"PROCESSOR_CONFIG":    true,
+	"PROCESSOR_DATA_DIR":  true,
+	"PROCESSOR_BIN":       true,
+	"PROCESSOR_LOG":       true,
+	"PROCESSOR_LOG_LEVEL": true,
+}
+
+// 取值引用前缀
+const (
+	refEnv  = "env:"
+	refFile = "file:"
+)
+
+// redactedValue 敏感配置在日志与状态输出中的占位
+const redactedValue = "******"
+
+// applyEnvOverrides 用 PROCESSOR_* 环境变量覆盖配置项，返回被覆盖的配置键（已排序）
+func applyEnvOverrides(kv map[string]string, environ []string) []string {
+	var keys []string
+	for _, e := range environ {
+		name, val, ok := strings.Cut(e, "=")
+		if !ok || !strings.HasPrefix(name, envOverridePrefix) || reservedEnv[name] {
+			continue
+		}
+		key := strings.ToLower(name)
+		kv[key] = unquote(strings.TrimSpace(val))
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolveRefs 解析 env:NAME 与 file:/path 形式的取值，返回使用了引用的配置键（已排序）；
+// untrusted 中的键（来自远程配置）按字面值保留，不读取本机环境变量与文件，避免配置服务借此读取本地密钥
+func resolveRefs(kv map[string]string, untrusted map[string]bool) ([]string, error) {
+	var keys []string
+	for k, v := range kv {
+		if untrusted[k] {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(v, refEnv):
+			name := strings.TrimSpace(strings.TrimPrefix(v, refEnv))
+			val, ok := os.LookupEnv(name)
+			if !ok {
+				return nil, fmt.Errorf("%s 引用的环境变量 %s 未设置", k, name)
+			}
+			kv[k] = strings.TrimSpace(val)
+		case strings.HasPrefix(v, refFile):
+			path := strings.TrimSpace(strings.TrimPrefix(v, refFile))
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s 引用的文件读取失败: %w", k, err)
+			}
+			kv[k] = strings.TrimSpace(string(raw))
+		default:
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// IsSecretKey 判断配置键是否为密码/token 等敏感项
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range []string{"_pass", "_password", "_token", "_secret", "_headers"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact 敏感项以及取值来自 env:/file: 引用的项返回占位符，其余原样返回
+func (c *ProcessorConfig) Redact(key, value string) string {
+	if value == "" {
+		return value
+	}
+	if IsSecretKey(key) {
+		return redactedValue
+	}
+	for _, k := range c.ResolvedRefs {
+		if k == key {
+			return redactedValue
+		}
+	}
+	return value
+}
+
+// Redacted 返回全部 processor_* 配置的副本，敏感项已脱敏
+func (c *ProcessorConfig) Redacted() map[string]string {
+	out := make(map[string]string, len(c.Values))
+	for k, v := range c.Values {
+		out[k] = c.Redact(k, v)
+	}
+	return out
+}
+
+// LogValue 日志输出时隐藏密码
+func (t FTPTarget) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", t.Name),
+		slog.String("host", t.Host),
+		slog.Int("port", t.Port),
+		slog.String("user", t.User),
+		slog.String("pass", RedactValue(t.Pass)),
+		slog.String("dir", t.Dir),
+	)
+}
+
+// RedactValue 非空取值返回占位符
+func RedactValue(value string) string {
+	if value == "" {
+		return value
+	}
+	return redactedValue
+}
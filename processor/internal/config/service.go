@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmacct/processor/internal/classify"
+)
+
+// ServiceConfig 按 (协议, 端口) 识别服务：计数始终进行，Columns 开启时追加 service、app_category 列
+type ServiceConfig struct {
+	Columns   bool
+	File      string   // 附加服务表（CSV：proto,port,service,category），覆盖内置表
+	Overrides []string // proto[/port]=service[:category]，覆盖内置表与服务表文件
+}
+
+// parseServices 解析 processor_service_*
+func parseServices(kv map[string]string) (ServiceConfig, error) {
+	s := ServiceConfig{File: kv[processorPrefix+"service_file"]}
+	if v, ok := kv[processorPrefix+"service_columns"]; ok {
+		b, err := parseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("processor_service_columns 解析失败: %w", err)
+		}
+		s.Columns = b
+	}
+	for _, o := range strings.Split(kv[processorPrefix+"service_overrides"], ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			s.Overrides = append(s.Overrides, o)
+		}
+	}
+	return s, nil
+}
+
+// validateServices 加载一次服务表，使文件与覆盖项的错误在启动或 check-config 时即报出
+func validateServices(s *ServiceConfig) error {
+	if _, err := classify.New(s.File, s.Overrides); err != nil {
+		return fmt.Errorf("processor_service_* 无效: %w", err)
+	}
+	return nil
+}
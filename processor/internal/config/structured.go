@@ -0,0 +1,297 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 结构化配置文件格式
+const (
+	formatYAML = "yaml"
+	formatTOML = "toml"
+)
+
+// configFileKey pmacct.conf 中引用结构化配置文件的配置项
+const configFileKey = processorPrefix + "config_file"
+
+// configSources 读取到的全部配置来源
+type configSources struct {
+	kv         map[string]string // 合并后的 processor_* 键值
+	contents   [][]byte          // 参与配置哈希的文件内容（按读取顺序）
+	structured string            // 使用的结构化配置文件路径（未使用时为空）
+	shadowed   []string          // 结构化文件中被 pmacct.conf 同名配置覆盖的键
+	remote     []string          // 来自远程配置的键
+	pmacct     map[string]string // pmacct.conf 中非 processor_* 的设置（-config 直接指向结构化文件时为空）
+}
+
+// structuredFormat 按扩展名判断是否为结构化配置文件
+func structuredFormat(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML, true
+	case ".toml":
+		return formatTOML, true
+	}
+	return "", false
+}
+
+// readSources 读取配置：-config 可直接指向 YAML/TOML 文件；
+// 指向 pmacct.conf 时解析 processor_* 行，并合并 processor_config_file 引用的结构化文件（pmacct.conf 中的同名配置优先）
+func readSources(configPath string) (*configSources, error) {
+	if _, err := os.Stat(configPath); err != nil {
+		return nil, fmt.Errorf("配置文件不存在: %w", err)
+	}
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	if format, ok := structuredFormat(configPath); ok {
+		kv, err := parseStructuredConfig(content, format)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", configPath, err)
+		}
+		return &configSources{kv: kv, contents: [][]byte{content}, structured: configPath}, nil
+	}
+
+	src := &configSources{
+		kv:       parseProcessorConfig(string(content)),
+		contents: [][]byte{content},
+		pmacct:   parsePmacctSettings(string(content)),
+	}
+	path := src.kv[configFileKey]
+	if path == "" {
+		return src, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+	format, ok := structuredFormat(path)
+	if !ok {
+		return nil, fmt.Errorf("%s 仅支持 .yaml/.yml/.toml 文件: %s", configFileKey, path)
+	}
+	sc, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", configFileKey, err)
+	}
+	skv, err := parseStructuredConfig(sc, format)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	for k, v := range skv {
+		if _, ok := src.kv[k]; ok {
+			src.shadowed = append(src.shadowed, k)
+			continue
+		}
+		src.kv[k] = v
+	}
+	sort.Strings(src.shadowed)
+	src.contents = append(src.contents, sc)
+	src.structured = path
+	return src, nil
+}
+
+// parseStructuredConfig 解析 YAML/TOML 配置并展开为等价的 processor_* 扁平键：
+// 嵌套段以 _ 连接（status_report.url -> processor_status_report_url），标量列表以逗号连接，
+// upload.targets 展开为 processor_upload_targets 与 processor_upload_target_<name>_*（pipelines、zones、filters 同理），
+// otlp.headers 映射展开为 k=v 列表
+func parseStructuredConfig(content []byte, format string) (map[string]string, error) {
+	var doc map[string]interface{}
+	switch format {
+	case formatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	case formatTOML:
+		if _, err := toml.NewDecoder(bytes.NewReader(content)).Decode(&doc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的配置格式: %s", format)
+	}
+
+	kv := make(map[string]string)
+	if err := flattenConfig(kv, strings.TrimSuffix(processorPrefix, "_"), doc); err != nil {
+		return nil, err
+	}
+	return kv, nil
+}
+
+func flattenConfig(kv map[string]string, key string, v interface{}) error {
+	switch key {
+	case processorPrefix + "upload_targets":
+		if _, ok := v.(string); !ok {
+			return flattenNamed(kv, "upload.targets", processorPrefix+"upload_targets", processorPrefix+"upload_target_", v)
+		}
+	case processorPrefix + "pipelines":
+		if _, ok := v.(string); !ok {
+			return flattenNamed(kv, "pipelines", processorPrefix+"pipelines", processorPrefix+"pipeline_", v)
+		}
+	case processorPrefix + "zones":
+		if _, ok := v.(string); !ok {
+			return flattenNamed(kv, "zones", processorPrefix+"zones", processorPrefix+"zone_", v)
+		}
+	case processorPrefix + "filters":
+		if _, ok := v.(string); !ok {
+			return flattenNamed(kv, "filters", processorPrefix+"filters", processorPrefix+"filter_", v)
+		}
+	case processorPrefix + "otlp_headers":
+		if m, ok := v.(map[string]interface{}); ok {
+			return flattenPairs(kv, key, m)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if err := flattenConfig(kv, key+"_"+normalizeKey(k), child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			s, ok := scalarString(item)
+			if !ok {
+				return fmt.Errorf("%s: 仅支持标量列表", key)
+			}
+			items = append(items, s)
+		}
+		kv[key] = strings.Join(items, ",")
+	default:
+		s, ok := scalarString(val)
+		if !ok {
+			return fmt.Errorf("%s: 不支持的取值类型 %T", key, val)
+		}
+		kv[key] = s
+	}
+	return nil
+}
+
+// flattenNamed 展开命名项列表（upload.targets、pipelines、zones、filters）：
+// 支持列表（每项含 name）或以名称为键的映射两种写法，列表保持声明顺序；
+// 项内的标量列表以逗号连接，映射展开为 k=v 列表
+func flattenNamed(kv map[string]string, path, listKey, itemPrefix string, v interface{}) error {
+	type entry struct {
+		name   string
+		fields map[string]interface{}
+	}
+	var items []entry
+	// TOML 的 [[upload.targets]] 等数组表解码为 []map[string]interface{}
+	if list, ok := v.([]map[string]interface{}); ok {
+		arr := make([]interface{}, len(list))
+		for i, m := range list {
+			arr[i] = m
+		}
+		v = arr
+	}
+	switch val := v.(type) {
+	case []interface{}:
+		for i, raw := range val {
+			m, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s[%d] 需为映射", path, i)
+			}
+			name, _ := scalarString(m["name"])
+			if name == "" {
+				return fmt.Errorf("%s[%d] 缺少 name", path, i)
+			}
+			items = append(items, entry{name: name, fields: m})
+		}
+	case map[string]interface{}:
+		names := make([]string, 0, len(val))
+		for name := range val {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m, ok := val[name].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s 需为映射", path, name)
+			}
+			items = append(items, entry{name: name, fields: m})
+		}
+	default:
+		return fmt.Errorf("%s 需为列表或映射", path)
+	}
+
+	names := make([]string, 0, len(items))
+	for _, t := range items {
+		names = append(names, t.name)
+		for field, fv := range t.fields {
+			if field == "name" {
+				continue
+			}
+			key := itemPrefix + t.name + "_" + normalizeKey(field)
+			switch fval := fv.(type) {
+			case []interface{}:
+				// 如 zones 的 prefixes 列表
+				if err := flattenConfig(kv, key, fval); err != nil {
+					return err
+				}
+			case map[string]interface{}:
+				// 如 zones 的 labels 映射
+				if err := flattenPairs(kv, key, fval); err != nil {
+					return err
+				}
+			default:
+				s, ok := scalarString(fv)
+				if !ok {
+					return fmt.Errorf("%s.%s.%s 需为标量", path, t.name, field)
+				}
+				kv[key] = s
+			}
+		}
+	}
+	kv[listKey] = strings.Join(names, ",")
+	return nil
+}
+
+func flattenPairs(kv map[string]string, key string, m map[string]interface{}) error {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		s, ok := scalarString(v)
+		if !ok {
+			return fmt.Errorf("%s.%s 需为标量", key, k)
+		}
+		pairs = append(pairs, k+"="+s)
+	}
+	sort.Strings(pairs)
+	kv[key] = strings.Join(pairs, ",")
+	return nil
+}
+
+func normalizeKey(k string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(k)), "-", "_")
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return val, true
+	case bool:
+		return strconv.FormatBool(val), true
+	case int:
+		return strconv.Itoa(val), true
+	case int64:
+		return strconv.FormatInt(val, 10), true
+	case uint64:
+		return strconv.FormatUint(val, 10), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	}
+	return "", false
+}
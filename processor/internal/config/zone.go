@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/pmacct/processor/internal/iptrie"
+)
+
+// zoneFields processor_zone_<name>_<field> 支持的字段
+var zoneFields = []string{"prefixes", "labels"}
+
+// ZoneConfig 一个本地网络区域：若干前缀及其标签
+type ZoneConfig struct {
+	Name     string
+	Prefixes []netip.Prefix
+	Labels   map[string]string // 如 site=bj,tenant=acme
+}
+
+// ZoningConfig 本地前缀与区域标签（未配置区域则不启用）
+type ZoningConfig struct {
+	Zones     []ZoneConfig
+	LabelKeys []string // 输出的标签列（src_<label>、dst_<label>），默认为各区域标签键的并集（按字母序）
+}
+
+// Enabled 是否启用区域标记
+func (z ZoningConfig) Enabled() bool {
+	return len(z.Zones) > 0
+}
+
+// parseZones 解析 processor_zones 列出的区域
+func parseZones(kv map[string]string) (ZoningConfig, error) {
+	var z ZoningConfig
+	raw := kv[processorPrefix+"zones"]
+	if strings.TrimSpace(raw) == "" {
+		return z, nil
+	}
+	seen := make(map[string]bool)
+	prefixes := make(map[netip.Prefix]string)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !pipelineNameRe.MatchString(name) {
+			return z, fmt.Errorf("processor_zones 中的区域名无效: %s", name)
+		}
+		if seen[name] {
+			return z, fmt.Errorf("processor_zones 中的区域名重复: %s", name)
+		}
+		seen[name] = true
+
+		zone := ZoneConfig{Name: name}
+		key := processorPrefix + "zone_" + name + "_prefixes"
+		for _, s := range strings.Split(kv[key], ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			p, err := netip.ParsePrefix(s)
+			if err == nil {
+				p, err = iptrie.UnmapPrefix(p)
+			}
+			if err != nil {
+				return z, fmt.Errorf("%s 中的前缀无效: %w", key, err)
+			}
+			if other, ok := prefixes[p]; ok {
+				return z, fmt.Errorf("前缀 %s 同时属于区域 %s 与 %s", p, other, name)
+			}
+			prefixes[p] = name
+			zone.Prefixes = append(zone.Prefixes, p)
+		}
+		if len(zone.Prefixes) == 0 {
+			return z, fmt.Errorf("区域 %s 需配置 %s", name, key)
+		}
+		if v := kv[processorPrefix+"zone_"+name+"_labels"]; strings.TrimSpace(v) != "" {
+			labels, err := parseLabels(v)
+			if err != nil {
+				return z, fmt.Errorf("processor_zone_%s_labels 解析失败: %w", name, err)
+			}
+			zone.Labels = labels
+		}
+		z.Zones = append(z.Zones, zone)
+	}
+
+	if v := kv[processorPrefix+"zone_labels"]; strings.TrimSpace(v) != "" {
+		for _, k := range strings.Split(v, ",") {
+			if k = normalizeKey(k); k != "" {
+				z.LabelKeys = append(z.LabelKeys, k)
+			}
+		}
+	} else {
+		keys := make(map[string]bool)
+		for _, zone := range z.Zones {
+			for k := range zone.Labels {
+				keys[k] = true
+			}
+		}
+		for k := range keys {
+			z.LabelKeys = append(z.LabelKeys, k)
+		}
+		sort.Strings(z.LabelKeys)
+	}
+	for _, k := range z.LabelKeys {
+		if k == "zone" {
+			return z, fmt.Errorf("标签名 zone 已由区域名占用（src_zone/dst_zone）")
+		}
+	}
+	return z, nil
+}
+
+// parseLabels 解析逗号分隔的 key=value 标签（键统一为小写），取值中不能含逗号
+func parseLabels(v string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(item, "=")
+		if k = normalizeKey(k); !ok || k == "" {
+			return nil, fmt.Errorf("无效的标签 %q，应为 key=value", item)
+		}
+		labels[k] = strings.TrimSpace(val)
+	}
+	return labels, nil
+}
+
+// isZoneKey 是否为 processor_zone_<name>_<field> 形式的配置项
+func isZoneKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, processorPrefix+"zone_")
+	if !ok {
+		return false
+	}
+	for _, f := range zoneFields {
+		if name, ok := strings.CutSuffix(rest, "_"+f); ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/pmacct/processor/internal/config"
 	"github.com/pmacct/processor/internal/host"
+	"github.com/pmacct/processor/internal/metrics"
 )
 
 type Collector struct {
@@ -24,6 +25,8 @@ type Collector struct {
 	dataDir  string
 	stopChan chan struct{}
 	doneChan chan struct{}
+	trigger  chan struct{}
+	interval chan time.Duration
 	host     string
 
 	procPayloadEnricher func(procName string) map[string]interface{}
@@ -42,6 +45,8 @@ func NewCollector(ctx context.Context, cfg config.DiagConfig, dataDir string) *C
 		dataDir:  dataDir,
 		stopChan: make(chan struct{}),
 		doneChan: make(chan struct{}),
+		trigger:  make(chan struct{}, 1),
+		interval: make(chan time.Duration, 1),
 		host:     host.FQDN(),
 	}
 }
@@ -67,6 +72,30 @@ func (c *Collector) Stop() {
 	<-c.doneChan
 }
 
+// Trigger 请求立即执行一次采集；在采集开始前的多次调用会合并为一次
+func (c *Collector) Trigger() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// SetInterval 运行时调整采集间隔；多次调用时以最后一次为准
+func (c *Collector) SetInterval(sec int) {
+	d := time.Duration(sec) * time.Second
+	for {
+		select {
+		case c.interval <- d:
+			return
+		default:
+		}
+		select {
+		case <-c.interval:
+		default:
+		}
+	}
+}
+
 func (c *Collector) run() {
 	defer close(c.doneChan)
 	ticker := time.NewTicker(time.Duration(c.cfg.IntervalSec) * time.Second)
@@ -77,6 +106,10 @@ func (c *Collector) run() {
 		select {
 		case <-ticker.C:
 			c.collectOnce()
+		case <-c.trigger:
+			c.collectOnce()
+		case d := <-c.interval:
+			ticker.Reset(d)
 		case <-c.stopChan:
 			return
 		case <-c.ctx.Done():
@@ -89,10 +122,12 @@ func (c *Collector) collectOnce() {
 	outDir := c.dataDir
 	stateDir := filepath.Join(c.dataDir, "diag")
 	if err := os.MkdirAll(outDir, 0755); err != nil {
+		metrics.DiagCollections.Inc(metrics.ResultFailure)
 		slog.Warn("diag: 创建目录失败", "err", err)
 		return
 	}
 	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		metrics.DiagCollections.Inc(metrics.ResultFailure)
 		slog.Warn("diag: 创建状态目录失败", "err", err)
 		return
 	}
@@ -105,12 +140,15 @@ func (c *Collector) collectOnce() {
 	envData, _, envAvailable, envPath := c.readEnvData(stateDir)
 
 	if len(syslogEntries) == 0 && len(procMetrics) == 0 && !envAvailable {
+		metrics.DiagCollections.Inc(metrics.ResultEmpty)
 		return
 	}
 	if err := writeDiagJSON(diagOut, syslogEntries, procMetrics, envData, envAvailable); err != nil {
+		metrics.DiagCollections.Inc(metrics.ResultFailure)
 		slog.Warn("diag: 写入诊断文件失败", "err", err)
 		return
 	}
+	metrics.DiagCollections.Inc(metrics.ResultSuccess)
 	if err := cleanupDiagSources(stateDir, envPath); err != nil {
 		slog.Warn("diag: 清理源文件失败", "err", err)
 	}
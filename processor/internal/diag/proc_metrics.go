@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/pmacct/processor/internal/host"
 )
 
 type procMetric struct {
@@ -140,7 +142,7 @@ func readTotalJiffies() (uint64, int) {
 func readProcSnapshots() []procSnapshot {
 	var snaps []procSnapshot
 	for _, name := range procNames {
-		pids := findPidsByName(name)
+		pids := host.PidsByName(name)
 		for _, pid := range pids {
 			if snap, ok := readProcSnapshot(pid); ok {
 				snaps = append(snaps, snap)
@@ -150,31 +152,6 @@ func readProcSnapshots() []procSnapshot {
 	return snaps
 }
 
-func findPidsByName(name string) []int {
-	entries, err := os.ReadDir("/proc")
-	if err != nil {
-		return nil
-	}
-	var pids []int
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		pid, err := strconv.Atoi(entry.Name())
-		if err != nil {
-			continue
-		}
-		comm, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm"))
-		if err != nil {
-			continue
-		}
-		if strings.TrimSpace(string(comm)) == name {
-			pids = append(pids, pid)
-		}
-	}
-	return pids
-}
-
 func readProcSnapshot(pid int) (procSnapshot, bool) {
 	statPath := filepath.Join("/proc", strconv.Itoa(pid), "stat")
 	raw, err := os.ReadFile(statPath)
@@ -0,0 +1,44 @@
+package enrich
+
+import (
+	"strings"
+
+	"github.com/pmacct/processor/internal/model"
+)
+
+// Stage 富化阶段：对已校验的流追加列
+type Stage interface {
+	// Name 阶段名（用于日志与状态输出）
+	Name() string
+	// Columns 追加的列名（按输出顺序）
+	Columns() []string
+	// Enrich 在 f.Extra 末尾追加与 Columns 等长的取值
+	Enrich(f *model.Flow)
+}
+
+// Chain 按顺序执行的富化阶段
+type Chain []Stage
+
+// Columns 全部阶段追加的列名
+func (c Chain) Columns() []string {
+	var out []string
+	for _, s := range c {
+		out = append(out, s.Columns()...)
+	}
+	return out
+}
+
+// Apply 依次执行各阶段
+func (c Chain) Apply(f *model.Flow) {
+	for _, s := range c {
+		s.Enrich(f)
+	}
+}
+
+// csvSafe 去除取值中会破坏 CSV 列结构的字符（逗号替换为空格）
+func csvSafe(s string) string {
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return strings.NewReplacer(",", " ", "\"", "", "\r", "", "\n", " ").Replace(s)
+}
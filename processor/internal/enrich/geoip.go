@@ -0,0 +1,317 @@
+package enrich
+
+import (
+	"container/list"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// GeoIP 按 SRC_IP/DST_IP 查询本地 .mmdb 库并追加 src_<字段>、dst_<字段> 列
+// 库文件整体读入内存，文件变化（mtime/大小）后重新加载并原子替换，查询无需加锁
+type GeoIP struct {
+	cfg     config.GeoIPConfig
+	columns []string
+	city    *mmdb
+	asn     *mmdb
+	cache   *lruCache // 为 nil 表示不缓存
+}
+
+// geoInfo 单个 IP 的查询结果（按 cfg.Fields 顺序）
+type geoInfo []string
+
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+type asnRecord struct {
+	Number uint   `maxminddb:"autonomous_system_number"`
+	Org    string `maxminddb:"autonomous_system_organization"`
+}
+
+// NewGeoIP 加载配置中的 .mmdb 库（启动时加载失败直接返回错误）
+func NewGeoIP(cfg config.GeoIPConfig) (*GeoIP, error) {
+	g := &GeoIP{cfg: cfg}
+	for _, side := range []string{"src", "dst"} {
+		for _, f := range cfg.Fields {
+			g.columns = append(g.columns, side+"_"+f)
+		}
+	}
+	var err error
+	if cfg.CityDB != "" {
+		if g.city, err = openMMDB("city", cfg.CityDB); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ASNDB != "" {
+		if g.asn, err = openMMDB("asn", cfg.ASNDB); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CacheSize > 0 {
+		g.cache = newLRUCache(cfg.CacheSize)
+	}
+	return g, nil
+}
+
+// Name 实现 Stage
+func (g *GeoIP) Name() string { return "geoip" }
+
+// Columns 实现 Stage
+func (g *GeoIP) Columns() []string { return g.columns }
+
+// Enrich 实现 Stage
+func (g *GeoIP) Enrich(f *model.Flow) {
+	f.Extra = append(f.Extra, g.lookup(f.SrcIP)...)
+	f.Extra = append(f.Extra, g.lookup(f.DstIP)...)
+}
+
+func (g *GeoIP) lookup(ip netip.Addr) geoInfo {
+	var gen uint64
+	if g.cache != nil {
+		info, ok, cacheGen := g.cache.get(ip)
+		if ok {
+			metrics.GeoIPLookups.Inc(metrics.GeoResultCacheHit)
+			return info
+		}
+		gen = cacheGen
+	}
+
+	var city cityRecord
+	var asn asnRecord
+	found := false
+	if g.city != nil {
+		ok, err := g.city.lookup(ip, &city)
+		if err != nil {
+			metrics.GeoIPLookups.Inc(metrics.GeoResultError)
+			slog.Debug("GeoIP City 查询失败", "ip", ip, "err", err)
+		}
+		found = found || ok
+	}
+	if g.asn != nil {
+		ok, err := g.asn.lookup(ip, &asn)
+		if err != nil {
+			metrics.GeoIPLookups.Inc(metrics.GeoResultError)
+			slog.Debug("GeoIP ASN 查询失败", "ip", ip, "err", err)
+		}
+		found = found || ok
+	}
+	if found {
+		metrics.GeoIPLookups.Inc(metrics.GeoResultFound)
+	} else {
+		metrics.GeoIPLookups.Inc(metrics.GeoResultNotFound)
+	}
+
+	info := make(geoInfo, len(g.cfg.Fields))
+	for i, field := range g.cfg.Fields {
+		switch field {
+		case config.GeoFieldCountry:
+			info[i] = city.Country.ISOCode
+		case config.GeoFieldCity:
+			info[i] = csvSafe(city.City.Names[g.cfg.Language])
+		case config.GeoFieldASN:
+			if asn.Number > 0 {
+				info[i] = strconv.FormatUint(uint64(asn.Number), 10)
+			}
+		case config.GeoFieldASOrg:
+			info[i] = csvSafe(asn.Org)
+		}
+	}
+	if g.cache != nil {
+		g.cache.put(ip, info, gen)
+	}
+	return info
+}
+
+// Run 定期检查库文件变化并重新加载，直到 done 关闭
+func (g *GeoIP) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(g.cfg.CheckIntervalSec) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			reloaded := false
+			for _, db := range []*mmdb{g.city, g.asn} {
+				if db != nil && db.reloadIfChanged() {
+					reloaded = true
+				}
+			}
+			if reloaded && g.cache != nil {
+				g.cache.clear()
+			}
+		}
+	}
+}
+
+// Status 各库的路径、构建时间与加载时间（用于 /status）
+func (g *GeoIP) Status() map[string]interface{} {
+	out := map[string]interface{}{"columns": g.columns}
+	for _, db := range []*mmdb{g.city, g.asn} {
+		if db == nil {
+			continue
+		}
+		cur := db.cur.Load()
+		out[db.name] = map[string]interface{}{
+			"path":      db.path,
+			"type":      cur.reader.Metadata.DatabaseType,
+			"build_at":  time.Unix(int64(cur.reader.Metadata.BuildEpoch), 0).UTC().Format(time.RFC3339),
+			"loaded_at": cur.loadedAt.UTC().Format(time.RFC3339),
+		}
+	}
+	if g.cache != nil {
+		out["cache_entries"] = g.cache.len()
+	}
+	return out
+}
+
+// mmdb 一个可热替换的 .mmdb 库
+type mmdb struct {
+	name string
+	path string
+	cur  atomic.Pointer[mmdbVersion]
+}
+
+type mmdbVersion struct {
+	reader   *maxminddb.Reader
+	modTime  time.Time
+	size     int64
+	loadedAt time.Time
+}
+
+func openMMDB(name, path string) (*mmdb, error) {
+	db := &mmdb{name: name, path: path}
+	v, err := loadMMDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("加载 GeoIP %s 库失败: %w", name, err)
+	}
+	db.cur.Store(v)
+	slog.Info("GeoIP 库已加载", "db", name, "path", path, "type", v.reader.Metadata.DatabaseType)
+	return db, nil
+}
+
+func loadMMDB(path string) (*mmdbVersion, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbVersion{reader: reader, modTime: st.ModTime(), size: st.Size(), loadedAt: time.Now()}, nil
+}
+
+// reloadIfChanged 文件 mtime 或大小变化时重新加载；加载失败保留旧库
+func (db *mmdb) reloadIfChanged() bool {
+	cur := db.cur.Load()
+	st, err := os.Stat(db.path)
+	if err != nil {
+		slog.Warn("检查 GeoIP 库失败，继续使用已加载的库", "db", db.name, "path", db.path, "err", err)
+		return false
+	}
+	if st.ModTime().Equal(cur.modTime) && st.Size() == cur.size {
+		return false
+	}
+	next, err := loadMMDB(db.path)
+	if err != nil {
+		metrics.GeoIPReloads.Inc(db.name, metrics.ResultFailure)
+		slog.Error("重新加载 GeoIP 库失败，继续使用已加载的库", "db", db.name, "path", db.path, "err", err)
+		return false
+	}
+	db.cur.Store(next)
+	metrics.GeoIPReloads.Inc(db.name, metrics.ResultSuccess)
+	slog.Info("GeoIP 库已重新加载", "db", db.name, "path", db.path,
+		"build_at", time.Unix(int64(next.reader.Metadata.BuildEpoch), 0).UTC().Format(time.RFC3339))
+	return true
+}
+
+func (db *mmdb) lookup(ip netip.Addr, result any) (bool, error) {
+	_, ok, err := db.cur.Load().reader.LookupNetwork(ip.AsSlice(), result)
+	return ok, err
+}
+
+// lruCache 按 IP 缓存查询结果，超出容量时淘汰最久未使用的条目；
+// gen 在每次 clear（库重新加载）时递增，查询前取得的代数与当前不符时结果不写入缓存，
+// 避免库替换前开始的查询在清空后写回旧库的结果
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	gen   uint64
+	ll    *list.List
+	items map[netip.Addr]*list.Element
+}
+
+type lruEntry struct {
+	ip   netip.Addr
+	info geoInfo
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{size: size, ll: list.New(), items: make(map[netip.Addr]*list.Element)}
+}
+
+// get 返回缓存的结果以及当前代数（未命中时用于随后的 put）
+func (c *lruCache) get(ip netip.Addr) (geoInfo, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[ip]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*lruEntry).info, true, c.gen
+	}
+	return nil, false, c.gen
+}
+
+func (c *lruCache) put(ip netip.Addr, info geoInfo, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if e, ok := c.items[ip]; ok {
+		e.Value.(*lruEntry).info = info
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[ip] = c.ll.PushFront(&lruEntry{ip: ip, info: info})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).ip)
+	}
+}
+
+func (c *lruCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.ll.Init()
+	c.items = make(map[netip.Addr]*list.Element)
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
@@ -0,0 +1,33 @@
+package enrich
+
+import (
+	"github.com/pmacct/processor/internal/classify"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// Services 按 (协议, 端口) 识别服务，追加 service,app_category 列
+type Services struct {
+	classifier *classify.Classifier
+}
+
+// NewServices 使用已加载的服务表
+func NewServices(c *classify.Classifier) *Services {
+	return &Services{classifier: c}
+}
+
+// Name 实现 Stage
+func (s *Services) Name() string { return "services" }
+
+// Columns 实现 Stage
+func (s *Services) Columns() []string { return []string{"service", "app_category"} }
+
+// Enrich 实现 Stage
+func (s *Services) Enrich(f *model.Flow) {
+	svc := s.classifier.Classify(f.Proto, f.SrcPort, f.DstPort)
+	f.Extra = append(f.Extra, svc.Name, svc.Category)
+}
+
+// Status 服务表条目数（用于 /status）
+func (s *Services) Status() map[string]interface{} {
+	return map[string]interface{}{"entries": s.classifier.Len()}
+}
@@ -0,0 +1,113 @@
+package enrich
+
+import (
+	"net/netip"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/iptrie"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// 流方向（以配置的本地前缀判断）
+const (
+	DirectionInbound  = "inbound"  // 外部 -> 本地
+	DirectionOutbound = "outbound" // 本地 -> 外部
+	DirectionInternal = "internal" // 本地 -> 本地
+	DirectionTransit  = "transit"  // 两端均不在本地前缀内
+)
+
+// Zones 按本地前缀（最长前缀匹配）标记两端所属区域与标签，并判断流方向：
+// 追加 src_zone,dst_zone,direction 以及 src_<label>…、dst_<label>… 列
+type Zones struct {
+	labelKeys []string
+	columns   []string
+	trie      *iptrie.Trie[*zoneEntry]
+	zones     int
+}
+
+type zoneEntry struct {
+	name   string
+	labels []string // 按 labelKeys 顺序
+}
+
+// NewZones 由区域配置构建前缀树
+func NewZones(cfg config.ZoningConfig) *Zones {
+	z := &Zones{labelKeys: cfg.LabelKeys, trie: iptrie.New[*zoneEntry](), zones: len(cfg.Zones)}
+	z.columns = []string{"src_zone", "dst_zone", "direction"}
+	for _, side := range []string{"src", "dst"} {
+		for _, k := range cfg.LabelKeys {
+			z.columns = append(z.columns, side+"_"+k)
+		}
+	}
+	for _, zc := range cfg.Zones {
+		e := &zoneEntry{name: zc.Name, labels: make([]string, len(cfg.LabelKeys))}
+		for i, k := range cfg.LabelKeys {
+			e.labels[i] = csvSafe(zc.Labels[k])
+		}
+		for _, p := range zc.Prefixes {
+			z.trie.Insert(p, e)
+		}
+	}
+	return z
+}
+
+// Name 实现 Stage
+func (z *Zones) Name() string { return "zones" }
+
+// Columns 实现 Stage
+func (z *Zones) Columns() []string { return z.columns }
+
+// Enrich 实现 Stage
+func (z *Zones) Enrich(f *model.Flow) {
+	src, srcLocal := z.trie.Lookup(f.SrcIP)
+	dst, dstLocal := z.trie.Lookup(f.DstIP)
+	dir := Direction(srcLocal, dstLocal)
+	metrics.FlowsByDirection.Inc(dir)
+
+	f.Extra = append(f.Extra, src.zoneName(), dst.zoneName(), dir)
+	f.Extra = append(f.Extra, src.labelValues(len(z.labelKeys))...)
+	f.Extra = append(f.Extra, dst.labelValues(len(z.labelKeys))...)
+}
+
+// Lookup 返回 addr 所属的区域名（不在本地前缀内时为空）
+func (z *Zones) Lookup(addr netip.Addr) string {
+	e, _ := z.trie.Lookup(addr)
+	return e.zoneName()
+}
+
+// Status 区域与前缀数量（用于 /status）
+func (z *Zones) Status() map[string]interface{} {
+	return map[string]interface{}{
+		"columns":  z.columns,
+		"zones":    z.zones,
+		"prefixes": z.trie.Len(),
+	}
+}
+
+// Direction 由两端是否属于本地前缀判断流方向
+func Direction(srcLocal, dstLocal bool) string {
+	switch {
+	case srcLocal && dstLocal:
+		return DirectionInternal
+	case srcLocal:
+		return DirectionOutbound
+	case dstLocal:
+		return DirectionInbound
+	}
+	return DirectionTransit
+}
+
+func (e *zoneEntry) zoneName() string {
+	if e == nil {
+		return ""
+	}
+	return e.name
+}
+
+func (e *zoneEntry) labelValues(n int) []string {
+	if e == nil {
+		return make([]string, n)
+	}
+	return e.labels
+}
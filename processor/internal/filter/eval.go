@@ -0,0 +1,102 @@
+package filter
+
+import (
+	"net/netip"
+
+	"github.com/pmacct/processor/internal/iptrie"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// node 编译后的表达式：对一条流求值
+type node func(f *model.Flow) bool
+
+// numField 取流的数值字段；port 等双向字段返回两个取值：==、<、>、in 等任一端满足即匹配，
+// != 为 == 的取反（两端都不等于该值才匹配），与 ip != x 一致
+type numField func(f *model.Flow) []uint64
+
+// ipField 取流的地址字段；ip 返回源与目的两个地址
+type ipField func(f *model.Flow) []netip.Addr
+
+var numFields = map[string]numField{
+	"src_port":  func(f *model.Flow) []uint64 { return []uint64{uint64(f.SrcPort)} },
+	"dst_port":  func(f *model.Flow) []uint64 { return []uint64{uint64(f.DstPort)} },
+	"port":      func(f *model.Flow) []uint64 { return []uint64{uint64(f.SrcPort), uint64(f.DstPort)} },
+	"proto":     func(f *model.Flow) []uint64 { return []uint64{uint64(f.Proto)} },
+	"tos":       func(f *model.Flow) []uint64 { return []uint64{uint64(f.TOS)} },
+	"tcp_flags": func(f *model.Flow) []uint64 { return []uint64{uint64(f.TCPFlags)} },
+	"packets":   func(f *model.Flow) []uint64 { return []uint64{f.Packets} },
+	"bytes":     func(f *model.Flow) []uint64 { return []uint64{f.Bytes} },
+}
+
+var (
+	srcIP = func(f *model.Flow) []netip.Addr { return []netip.Addr{f.SrcIP} }
+	dstIP = func(f *model.Flow) []netip.Addr { return []netip.Addr{f.DstIP} }
+	anyIP = func(f *model.Flow) []netip.Addr { return []netip.Addr{f.SrcIP, f.DstIP} }
+)
+
+var ipFields = map[string]ipField{"src_ip": srcIP, "dst_ip": dstIP, "ip": anyIP}
+
+var netFuncs = map[string]ipField{"src_net": srcIP, "dst_net": dstIP, "net": anyIP}
+
+func constNode(v bool) node { return func(*model.Flow) bool { return v } }
+
+func notNode(n node) node { return func(f *model.Flow) bool { return !n(f) } }
+
+func andNode(a, b node) node { return func(f *model.Flow) bool { return a(f) && b(f) } }
+
+func orNode(a, b node) node { return func(f *model.Flow) bool { return a(f) || b(f) } }
+
+func numCmpNode(field numField, op string, v uint64) node {
+	var cmp func(x uint64) bool
+	switch op {
+	case "==":
+		cmp = func(x uint64) bool { return x == v }
+	case "!=":
+		return notNode(numCmpNode(field, "==", v))
+	case "<":
+		cmp = func(x uint64) bool { return x < v }
+	case "<=":
+		cmp = func(x uint64) bool { return x <= v }
+	case ">":
+		cmp = func(x uint64) bool { return x > v }
+	default:
+		cmp = func(x uint64) bool { return x >= v }
+	}
+	return func(f *model.Flow) bool {
+		for _, x := range field(f) {
+			if cmp(x) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func numInNode(field numField, ranges [][2]uint64) node {
+	return func(f *model.Flow) bool {
+		for _, x := range field(f) {
+			for _, r := range ranges {
+				if x >= r[0] && x <= r[1] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// netNode 地址属于任一前缀（最长前缀匹配树，前缀较多时也不退化为线性扫描）
+func netNode(field ipField, prefixes []netip.Prefix) node {
+	t := iptrie.New[struct{}]()
+	for _, p := range prefixes {
+		t.Insert(p, struct{}{})
+	}
+	return func(f *model.Flow) bool {
+		for _, a := range field(f) {
+			if t.Contains(a) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,84 @@
+// Package filter 流过滤表达式：配置中的表达式启动时编译一次，之后对每条已解析的流求值
+//
+// 示例：proto == tcp && dst_port in [443, 8443, 8000-8100] && !src_net(10.0.0.0/8)
+package filter
+
+import (
+	"fmt"
+
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// 过滤模式
+const (
+	ModeDrop = "drop" // 丢弃匹配任一规则的流
+	ModeKeep = "keep" // 仅保留匹配任一规则的流
+)
+
+// Rule 一条已编译的过滤规则
+type Rule struct {
+	Name  string
+	Expr  string
+	match node
+}
+
+// Compile 编译一条过滤表达式
+func Compile(name, expr string) (*Rule, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "多余的内容")
+	}
+	return &Rule{Name: name, Expr: expr, match: n}, nil
+}
+
+// Match 该流是否匹配本规则
+func (r *Rule) Match(f *model.Flow) bool {
+	return r.match(f)
+}
+
+// Filter 一组规则及其模式
+type Filter struct {
+	mode  string
+	rules []*Rule
+}
+
+// New 构建过滤器；mode 为 ModeDrop 或 ModeKeep
+func New(mode string, rules []*Rule) (*Filter, error) {
+	if mode != ModeDrop && mode != ModeKeep {
+		return nil, fmt.Errorf("未知的过滤模式: %s", mode)
+	}
+	return &Filter{mode: mode, rules: rules}, nil
+}
+
+// Keep 对流求值所有规则（逐条计数匹配次数），返回是否保留该流
+func (fl *Filter) Keep(f *model.Flow) bool {
+	matched := false
+	for _, r := range fl.rules {
+		if r.match(f) {
+			matched = true
+			metrics.FilterMatches.Inc(r.Name)
+		}
+	}
+	if fl.mode == ModeKeep {
+		return matched
+	}
+	return !matched
+}
+
+// Status 过滤器概况（写入 /status）
+func (fl *Filter) Status() map[string]interface{} {
+	rules := make([]map[string]string, 0, len(fl.rules))
+	for _, r := range fl.rules {
+		rules = append(rules, map[string]string{"name": r.Name, "expr": r.Expr})
+	}
+	return map[string]interface{}{"mode": fl.mode, "rules": rules}
+}
@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/pmacct/processor/internal/model"
+)
+
+func testFlow(src, dst string, srcPort, dstPort uint16, proto uint8) *model.Flow {
+	return &model.Flow{
+		SrcIP:   netip.MustParseAddr(src),
+		DstIP:   netip.MustParseAddr(dst),
+		SrcPort: srcPort,
+		DstPort: dstPort,
+		Proto:   proto,
+		Packets: 10,
+		Bytes:   1500,
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{in: "10.1.2.3", want: "10.1.2.3/32"},
+		{in: "2001:db8::/32", want: "2001:db8::/32"},
+		{in: "::ffff:10.1.2.3", want: "10.1.2.3/32"},
+		{in: "::ffff:10.0.0.0/104", want: "10.0.0.0/8"},
+		{in: "::ffff:192.168.1.0/120", want: "192.168.1.0/24"},
+		{in: "::ffff:192.168.1.7/120", want: "192.168.1.0/24"},
+		{in: "::ffff:0.0.0.0/96", want: "0.0.0.0/0"},
+		{in: "::ffff:0.0.0.0/80", wantErr: true},
+		{in: "10.0.0.0/33", wantErr: true},
+		{in: "not-an-ip", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parsePrefix(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parsePrefix(%q) = %s，应返回错误", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePrefix(%q) 返回错误: %v", tc.in, err)
+			continue
+		}
+		if !got.IsValid() || got.String() != tc.want {
+			t.Errorf("parsePrefix(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	web := testFlow("10.0.0.1", "192.0.2.10", 51000, 443, 6)
+	dns := testFlow("10.0.0.2", "198.51.100.53", 53000, 53, 17)
+	local := testFlow("10.0.0.3", "10.0.0.4", 443, 443, 6)
+
+	tests := []struct {
+		expr string
+		flow *model.Flow
+		want bool
+	}{
+		// != 为 == 的取反：双向字段两端都不等于该值才匹配
+		{"src_ip != 10.0.0.1", web, false},
+		{"!(src_ip == 10.0.0.1)", web, false},
+		{"src_ip != 10.0.0.2", web, true},
+		{"ip != 10.0.0.1", web, false},
+		{"!(ip == 10.0.0.1)", web, false},
+		{"ip != 203.0.113.1", web, true},
+		{"port != 443", web, false},
+		{"!(port == 443)", web, false},
+		{"port != 443", dns, true},
+		{"port != 443", local, false},
+		{"dst_port != 443", web, false},
+		{"src_port != 443", web, true},
+
+		// in [..] 列表与范围
+		{"dst_port in [80, 443]", web, true},
+		{"dst_port in [8000-8100]", web, false},
+		{"dst_port in [400-500]", web, true},
+		{"port in [50000-52000]", web, true},
+		{"port in [50000-52000]", dns, false},
+		{"dst_ip in [192.0.2.0/24, 198.51.100.0/24]", dns, true},
+		{"src_ip in [::ffff:10.0.0.0/104]", web, true},
+
+		// 协议名与协议号
+		{"proto == tcp", web, true},
+		{"proto == TCP", web, true},
+		{"proto == udp", web, false},
+		{"proto == 17", dns, true},
+		{"proto in [tcp, udp]", dns, true},
+		{"proto != tcp", dns, true},
+
+		// 网段函数与组合
+		{"src_net(10.0.0.0/8) && !dst_net(10.0.0.0/8)", web, true},
+		{"src_net(10.0.0.0/8) && !dst_net(10.0.0.0/8)", local, false},
+		{"net(::ffff:192.0.2.0/120)", web, true},
+		{"proto == udp || dst_port == 443", web, true},
+		{"proto == udp && dst_port == 443", web, false},
+		{"bytes >= 1500 && packets < 11", web, true},
+		{"true", web, true},
+		{"false", web, false},
+	}
+	for _, tc := range tests {
+		r, err := Compile("t", tc.expr)
+		if err != nil {
+			t.Errorf("Compile(%q) 返回错误: %v", tc.expr, err)
+			continue
+		}
+		if got := r.Match(tc.flow); got != tc.want {
+			t.Errorf("%q 对 %s:%d -> %s:%d = %v, want %v", tc.expr,
+				tc.flow.SrcIP, tc.flow.SrcPort, tc.flow.DstIP, tc.flow.DstPort, got, tc.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  string // 错误信息中的位置前缀
+	}{
+		{"dst_port == 443 &&", "位置 19"},
+		{"dst_port === 443", "位置 12"},
+		{"dst_port # 443", "位置 10"},
+		{"foo == 1", "位置 1"},
+		{"src_ip > 10.0.0.1", "位置 8"},
+		{"src_ip == 10.0.0", "位置 11"},
+		{"dst_port in [443, 500-400]", "位置 19"},
+		{"dst_port in [443", "位置 17"},
+		{"src_net(10.0.0.0/33)", "位置 9"},
+		{"(proto == tcp", "位置 14"},
+		{"proto == tcp)", "位置 13"},
+		{"proto == bogus", "位置 10"},
+	}
+	for _, tc := range tests {
+		_, err := Compile("t", tc.expr)
+		if err == nil {
+			t.Errorf("Compile(%q) 应返回错误", tc.expr)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tc.pos) {
+			t.Errorf("Compile(%q) 错误 %q，应以 %q 开头", tc.expr, err, tc.pos)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package filter
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/pmacct/processor/internal/iptrie"
+)
+
+// 词法单元类型
+const (
+	tokEOF    = iota
+	tokWord   // 标识符、数字、IP、CIDR、端口范围
+	tokOp     // == != < <= > >=
+	tokAnd    // &&
+	tokOr     // ||
+	tokNot    // !
+	tokLParen // (
+	tokRParen // )
+	tokLBrack // [
+	tokRBrack // ]
+	tokComma  // ,
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// lex 将表达式切分为词法单元；单词可包含字母、数字以及 _ . : / -（以便直接书写 IP、CIDR 与端口范围）
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isWordChar(c):
+			j := i
+			for j < len(src) && isWordChar(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tokWord, src[i:j], i})
+			i = j
+		default:
+			two := ""
+			if i+1 < len(src) {
+				two = src[i : i+2]
+			}
+			switch {
+			case two == "&&":
+				toks = append(toks, token{tokAnd, two, i})
+				i += 2
+			case two == "||":
+				toks = append(toks, token{tokOr, two, i})
+				i += 2
+			case two == "==" || two == "!=" || two == "<=" || two == ">=":
+				toks = append(toks, token{tokOp, two, i})
+				i += 2
+			case c == '<' || c == '>':
+				toks = append(toks, token{tokOp, string(c), i})
+				i++
+			case c == '!':
+				toks = append(toks, token{tokNot, "!", i})
+				i++
+			case c == '(' || c == ')' || c == '[' || c == ']' || c == ',':
+				kind := map[byte]int{'(': tokLParen, ')': tokRParen, '[': tokLBrack, ']': tokRBrack, ',': tokComma}[c]
+				toks = append(toks, token{kind, string(c), i})
+				i++
+			default:
+				return nil, fmt.Errorf("位置 %d: 无法识别的字符 %q", i+1, c)
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '.' || c == ':' || c == '/' || c == '-'
+}
+
+// parser 递归下降解析：
+//
+//	expr    := and ( "||" and )*
+//	and     := unary ( "&&" unary )*
+//	unary   := "!" unary | primary
+//	primary := "(" expr ")" | "true" | "false" | call | field op value | field "in" list
+//	call    := ("src_net" | "dst_net" | "net") "(" cidr ( "," cidr )* ")"
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind int, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "此处应为 %s", what)
+	}
+	return t, nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	near := t.text
+	if t.kind == tokEOF {
+		near = "表达式末尾"
+	}
+	return fmt.Errorf("位置 %d（%s）: %s", t.pos+1, near, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode(inner), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokWord:
+	default:
+		return nil, p.errorf(t, "此处应为字段、函数或 (")
+	}
+
+	word := strings.ToLower(t.text)
+	switch word {
+	case "true":
+		return constNode(true), nil
+	case "false":
+		return constNode(false), nil
+	}
+	if sides, ok := netFuncs[word]; ok {
+		return p.parseNetCall(t, sides)
+	}
+	if f, ok := numFields[word]; ok {
+		return p.parseNumCompare(t, f)
+	}
+	if f, ok := ipFields[word]; ok {
+		return p.parseIPCompare(t, f)
+	}
+	return nil, p.errorf(t, "未知的字段或函数 %s", t.text)
+}
+
+// parseNetCall src_net(10.0.0.0/8, ...)：地址属于任一前缀
+func (p *parser) parseNetCall(name token, f ipField) (node, error) {
+	if _, err := p.expect(tokLParen, "("); err != nil {
+		return nil, err
+	}
+	var prefixes []netip.Prefix
+	for {
+		t, err := p.expect(tokWord, "CIDR")
+		if err != nil {
+			return nil, err
+		}
+		pfx, err := parsePrefix(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "%s 的参数需为 IP 或 CIDR", name.text)
+		}
+		prefixes = append(prefixes, pfx)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen, ")"); err != nil {
+		return nil, err
+	}
+	return netNode(f, prefixes), nil
+}
+
+func (p *parser) parseNumCompare(name token, f numField) (node, error) {
+	t := p.next()
+	if t.kind == tokWord && strings.EqualFold(t.text, "in") {
+		ranges, err := p.parseNumList(name)
+		if err != nil {
+			return nil, err
+		}
+		return numInNode(f, ranges), nil
+	}
+	if t.kind != tokOp {
+		return nil, p.errorf(t, "%s 之后应为比较运算符或 in", name.text)
+	}
+	vt, err := p.expect(tokWord, "数值")
+	if err != nil {
+		return nil, err
+	}
+	v, err := parseNumValue(name.text, vt.text)
+	if err != nil {
+		return nil, p.errorf(vt, "%v", err)
+	}
+	return numCmpNode(f, t.text, v), nil
+}
+
+// parseNumList [443, 8443, 8000-8100]
+func (p *parser) parseNumList(name token) ([][2]uint64, error) {
+	if _, err := p.expect(tokLBrack, "["); err != nil {
+		return nil, err
+	}
+	var ranges [][2]uint64
+	for {
+		t, err := p.expect(tokWord, "数值或范围")
+		if err != nil {
+			return nil, err
+		}
+		lo, hi, isRange := strings.Cut(t.text, "-")
+		a, err := parseNumValue(name.text, lo)
+		if err != nil {
+			return nil, p.errorf(t, "%v", err)
+		}
+		b := a
+		if isRange {
+			if b, err = parseNumValue(name.text, hi); err != nil || b < a {
+				return nil, p.errorf(t, "无效的范围")
+			}
+		}
+		ranges = append(ranges, [2]uint64{a, b})
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRBrack, "]"); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func (p *parser) parseIPCompare(name token, f ipField) (node, error) {
+	t := p.next()
+	if t.kind == tokWord && strings.EqualFold(t.text, "in") {
+		if _, err := p.expect(tokLBrack, "["); err != nil {
+			return nil, err
+		}
+		var prefixes []netip.Prefix
+		for {
+			vt, err := p.expect(tokWord, "IP 或 CIDR")
+			if err != nil {
+				return nil, err
+			}
+			pfx, err := parsePrefix(vt.text)
+			if err != nil {
+				return nil, p.errorf(vt, "需为 IP 或 CIDR")
+			}
+			prefixes = append(prefixes, pfx)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokRBrack, "]"); err != nil {
+			return nil, err
+		}
+		return netNode(f, prefixes), nil
+	}
+	if t.kind != tokOp || (t.text != "==" && t.text != "!=") {
+		return nil, p.errorf(t, "%s 仅支持 ==、!= 与 in", name.text)
+	}
+	vt, err := p.expect(tokWord, "IP")
+	if err != nil {
+		return nil, err
+	}
+	addr, err := netip.ParseAddr(vt.text)
+	if err != nil {
+		return nil, p.errorf(vt, "需为 IP 地址")
+	}
+	n := netNode(f, []netip.Prefix{netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())})
+	if t.text == "!=" {
+		return notNode(n), nil
+	}
+	return n, nil
+}
+
+// parsePrefix 解析 CIDR，单个 IP 视为主机前缀；IPv4 映射前缀转换为 IPv4 前缀
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return p, err
+		}
+		return iptrie.UnmapPrefix(p)
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+// parseNumValue 解析数值；proto 字段可使用协议名（tcp、udp 等）
+func parseNumValue(field, s string) (uint64, error) {
+	if strings.EqualFold(field, "proto") {
+		if v, ok := protoByName[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s 不是有效的数值", s)
+	}
+	return v, nil
+}
+
+var protoByName = map[string]uint64{
+	"icmp": 1, "igmp": 2, "tcp": 6, "udp": 17, "gre": 47, "esp": 50, "ah": 51,
+	"ipv6-icmp": 58, "icmpv6": 58, "ospf": 89, "sctp": 132,
+}
@@ -0,0 +1,105 @@
+package iptrie
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Trie 按 IP 前缀做最长前缀匹配的二叉前缀树，IPv4 与 IPv6 分别建树
+// 建树完成后只读，可被多个 goroutine 并发查询
+type Trie[V any] struct {
+	v4  *node[V]
+	v6  *node[V]
+	len int
+}
+
+type node[V any] struct {
+	child [2]*node[V]
+	value V
+	set   bool
+}
+
+// New 创建空的前缀树
+func New[V any]() *Trie[V] {
+	return &Trie[V]{v4: &node[V]{}, v6: &node[V]{}}
+}
+
+// Insert 插入前缀（主机位会被清零），已存在时覆盖取值并返回 false；
+// 无效前缀（如 PrefixFrom 得到的负长度）不插入并返回 false，否则会挂在根节点上匹配全部地址
+func (t *Trie[V]) Insert(p netip.Prefix, v V) bool {
+	if !p.IsValid() {
+		return false
+	}
+	p = p.Masked()
+	addr := p.Addr()
+	n := t.root(addr)
+	b := addr.AsSlice()
+	for i := 0; i < p.Bits(); i++ {
+		bit := b[i/8] >> (7 - i%8) & 1
+		if n.child[bit] == nil {
+			n.child[bit] = &node[V]{}
+		}
+		n = n.child[bit]
+	}
+	existed := n.set
+	n.value, n.set = v, true
+	if !existed {
+		t.len++
+	}
+	return !existed
+}
+
+// Lookup 返回包含 addr 的最长前缀的取值
+func (t *Trie[V]) Lookup(addr netip.Addr) (V, bool) {
+	var best V
+	found := false
+	if !addr.IsValid() {
+		return best, false
+	}
+	addr = addr.Unmap()
+	n := t.root(addr)
+	b := addr.AsSlice()
+	for i := 0; n != nil; i++ {
+		if n.set {
+			best, found = n.value, true
+		}
+		if i == len(b)*8 {
+			break
+		}
+		n = n.child[b[i/8]>>(7-i%8)&1]
+	}
+	return best, found
+}
+
+// Contains 是否有任一前缀包含 addr
+func (t *Trie[V]) Contains(addr netip.Addr) bool {
+	_, ok := t.Lookup(addr)
+	return ok
+}
+
+// Len 前缀数量
+func (t *Trie[V]) Len() int {
+	return t.len
+}
+
+func (t *Trie[V]) root(addr netip.Addr) *node[V] {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// UnmapPrefix 将 IPv4 映射前缀（::ffff:a.b.c.d/n）转换为等价的 IPv4 前缀（长度减 96），其余前缀原样返回；
+// 结果已清零主机位。映射前缀长度不足 96 时无法表示为 IPv4 前缀，返回错误
+func UnmapPrefix(p netip.Prefix) (netip.Prefix, error) {
+	if !p.IsValid() {
+		return p, fmt.Errorf("无效的前缀 %s", p)
+	}
+	if !p.Addr().Is4In6() {
+		return p.Masked(), nil
+	}
+	if p.Bits() < 96 {
+		return netip.Prefix{}, fmt.Errorf("IPv4 映射前缀 %s 的长度需 >= 96", p)
+	}
+	return netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96).Masked(), nil
+}
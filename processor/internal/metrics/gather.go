@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"sort"
+)
+
+// 指标类型
+const (
+	KindCounter   = "counter"
+	KindGauge     = "gauge"
+	KindHistogram = "histogram"
+)
+
+// Family 单个指标的全部样本快照（供 OTLP 等非 Prometheus 出口使用）
+type Family struct {
+	Name    string
+	Help    string
+	Kind    string
+	Samples []Sample
+}
+
+// Sample 一组标签下的样本；直方图的 Buckets 为各桶非累计计数，末位对应 +Inf
+type Sample struct {
+	Labels  map[string]string
+	Value   float64
+	Count   uint64
+	Sum     float64
+	Bounds  []float64
+	Buckets []uint64
+}
+
+// Gather 按注册顺序返回所有指标快照，无样本的指标会被跳过
+func Gather() []Family {
+	registryMu.Lock()
+	cs := make([]collector, len(registry))
+	copy(cs, registry)
+	registryMu.Unlock()
+
+	out := make([]Family, 0, len(cs))
+	for _, c := range cs {
+		if f := c.collect(); len(f.Samples) > 0 {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func labelMap(names, values []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(names))
+	for i, n := range names {
+		if i < len(values) {
+			m[n] = values[i]
+		}
+	}
+	return m
+}
+
+func (c *Counter) collect() Family {
+	return Family{Name: c.name, Help: c.help, Kind: KindCounter, Samples: []Sample{{Value: float64(c.v.Load())}}}
+}
+
+func (c *CounterVec) collect() Family {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f := Family{Name: c.name, Help: c.help, Kind: KindCounter}
+	for _, k := range keys {
+		lc := c.values[k]
+		f.Samples = append(f.Samples, Sample{Labels: labelMap(c.labels, lc.values), Value: float64(lc.v.Load())})
+	}
+	return f
+}
+
+func (g *Gauge) collect() Family {
+	return Family{Name: g.name, Help: g.help, Kind: KindGauge, Samples: []Sample{{Value: g.Value()}}}
+}
+
+func (g *GaugeFunc) collect() Family {
+	f := Family{Name: g.name, Help: g.help, Kind: KindGauge}
+	if fn := g.fn.Load(); fn != nil {
+		f.Samples = []Sample{{Value: (*fn)()}}
+	}
+	return f
+}
+
+func (h *HistogramVec) collect() Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f := Family{Name: h.name, Help: h.help, Kind: KindHistogram}
+	for _, k := range keys {
+		hv := h.values[k]
+		// 内部按 Prometheus 方式累计计数，这里还原为逐桶计数
+		buckets := make([]uint64, len(h.buckets)+1)
+		var prev uint64
+		for i, n := range hv.counts {
+			buckets[i] = n - prev
+			prev = n
+		}
+		buckets[len(h.buckets)] = hv.count - prev
+		f.Samples = append(f.Samples, Sample{
+			Labels:  labelMap(h.labels, hv.values),
+			Count:   hv.count,
+			Sum:     hv.sum,
+			Bounds:  append([]float64(nil), h.buckets...),
+			Buckets: buckets,
+		})
+	}
+	return f
+}
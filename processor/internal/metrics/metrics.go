@@ -0,0 +1,304 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector 可输出为 Prometheus 文本格式并可导出快照的指标
+type collector interface {
+	write(w io.Writer)
+	collect() Family
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// WriteText 按注册顺序输出所有指标（Prometheus text format 0.0.4）
+func WriteText(w io.Writer) {
+	registryMu.Lock()
+	cs := make([]collector, len(registry))
+	copy(cs, registry)
+	registryMu.Unlock()
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// Handler 返回 /metrics 的 HTTP handler
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+// Counter 单调递增计数器
+type Counter struct {
+	name string
+	help string
+	v    atomic.Int64
+}
+
+// NewCounter 创建并注册计数器
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc()         { c.v.Add(1) }
+func (c *Counter) Add(n int64)  { c.v.Add(n) }
+func (c *Counter) Value() int64 { return c.v.Load() }
+func (c *Counter) write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %d\n", c.name, c.v.Load())
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.RWMutex
+	values map[string]*labeledCounter
+}
+
+type labeledCounter struct {
+	values []string
+	v      atomic.Int64
+}
+
+// NewCounterVec 创建并注册带标签的计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: map[string]*labeledCounter{}}
+	register(c)
+	return c
+}
+
+// Add 按标签值累加（标签值个数需与定义一致）
+func (c *CounterVec) Add(n int64, values ...string) {
+	c.get(values).v.Add(n)
+}
+
+// Inc 按标签值加一
+func (c *CounterVec) Inc(values ...string) {
+	c.get(values).v.Add(1)
+}
+
+// Snapshot 返回 标签值(以 | 连接) -> 计数 的快照
+func (c *CounterVec) Snapshot() map[string]int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]int64, len(c.values))
+	for k, lc := range c.values {
+		out[k] = lc.v.Load()
+	}
+	return out
+}
+
+func (c *CounterVec) get(values []string) *labeledCounter {
+	key := strings.Join(values, "|")
+	c.mu.RLock()
+	lc, ok := c.values[key]
+	c.mu.RUnlock()
+	if ok {
+		return lc
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if lc, ok := c.values[key]; ok {
+		return lc
+	}
+	lc = &labeledCounter{values: append([]string(nil), values...)}
+	c.values[key] = lc
+	return lc
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.mu.RLock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lc := c.values[k]
+		fmt.Fprintf(w, "%s%s %d\n", c.name, formatLabels(c.labels, lc.values), lc.v.Load())
+	}
+	c.mu.RUnlock()
+}
+
+// Gauge 可增可减的瞬时值
+type Gauge struct {
+	name string
+	help string
+	bits atomic.Uint64
+}
+
+// NewGauge 创建并注册 gauge
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64)  { g.bits.Store(math.Float64bits(v)) }
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+func (g *Gauge) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.Value()))
+}
+
+// GaugeFunc 抓取时通过回调取值的 gauge（回调未设置时不输出样本）
+type GaugeFunc struct {
+	name string
+	help string
+	fn   atomic.Pointer[func() float64]
+}
+
+// NewGaugeFunc 创建并注册回调 gauge
+func NewGaugeFunc(name, help string) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help}
+	register(g)
+	return g
+}
+
+// SetFunc 设置取值回调
+func (g *GaugeFunc) SetFunc(fn func() float64) {
+	g.fn.Store(&fn)
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	fn := g.fn.Load()
+	if fn == nil {
+		return
+	}
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat((*fn)()))
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+type histogram struct {
+	values []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 创建并注册带标签的直方图（buckets 需升序）
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: map[string]*histogram{}}
+	register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	key := strings.Join(values, "|")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogram{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		hv := h.values[k]
+		names := append(append([]string(nil), h.labels...), "le")
+		for i, b := range h.buckets {
+			vals := append(append([]string(nil), hv.values...), formatFloat(b))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, vals), hv.counts[i])
+		}
+		vals := append(append([]string(nil), hv.values...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, vals), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, hv.values), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, hv.values), hv.count)
+	}
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import "strconv"
+
+// 管道各阶段指标
+var (
+	IngestedLines    = NewCounter("processor_ingested_lines_total", "Data lines read from stdin (header excluded).")
+	InvalidLines     = NewCounter("processor_invalid_lines_total", "Lines rejected by the validator.")
+	DroppedLines     = NewCounter("processor_dropped_lines_total", "Valid lines dropped because the ingest channel was full.")
+	FilteredLines    = NewCounter("processor_filtered_lines_total", "Valid lines discarded by the flow filter.")
+	WrittenLines     = NewCounter("processor_written_lines_total", "Lines written to flow files.")
+	FlowPackets      = NewCounter("processor_flow_packets_total", "Sum of PACKETS over valid flows.")
+	FlowBytes        = NewCounter("processor_flow_bytes_total", "Sum of BYTES over valid flows.")
+	FlowsByProto     = NewCounterVec("processor_flows_by_protocol_total", "Valid flows by IP protocol.", "proto")
+	FlowsByService   = NewCounterVec("processor_flows_by_service_total", "Valid flows by service classified from protocol and port.", "service")
+	FlowsByDirection = NewCounterVec("processor_flows_by_direction_total", "Valid flows by direction relative to the configured local prefixes.", "direction")
+	PipelineLines    = NewCounterVec("processor_pipeline_lines_total", "Lines by pipeline and stage.", "pipeline", "stage")
+
+	ChannelDepth     = NewGaugeFunc("processor_ingest_channel_depth", "Lines currently queued between ingest and writer.")
+	ChannelCapacity  = NewGaugeFunc("processor_ingest_channel_capacity", "Capacity of the ingest channel.")
+	CurrentFileBytes = NewGaugeFunc("processor_current_file_bytes", "Uncompressed bytes written to the current flow file.")
+	CurrentFileAge   = NewGaugeFunc("processor_current_file_age_seconds", "Age of the current flow file.")
+	Rotations        = NewCounter("processor_file_rotations_total", "Flow files closed and renamed to .csv.gz.")
+
+	Uploads        = NewCounterVec("processor_uploads_total", "Upload attempts by target and result.", "target", "result")
+	UploadDuration = NewHistogramVec("processor_upload_duration_seconds", "Upload latency by target.",
+		[]float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "target")
+	BacklogFiles = NewGaugeFunc("processor_upload_backlog_files", "Local files waiting for upload.")
+	BacklogBytes = NewGaugeFunc("processor_upload_backlog_bytes", "Size of local files waiting for upload.")
+
+	DiagCollections = NewCounterVec("processor_diag_collections_total", "Diag collection runs by result.", "result")
+
+	GeoIPLookups = NewCounterVec("processor_geoip_lookups_total", "GeoIP lookups by result.", "result")
+	GeoIPReloads = NewCounterVec("processor_geoip_reloads_total", "GeoIP database reloads by database and result.", "db", "result")
+
+	FilterMatches = NewCounterVec("processor_filter_matches_total", "Flows matched by each filter rule.", "rule")
+
+	AnonymizedAddrs = NewCounterVec("processor_anonymized_addresses_total", "Addresses processed by the anonymizer, by result.", "result")
+
+	SamplingAnomalies = NewCounterVec("processor_sampling_anomalies_total", "Flows whose sampling correction was clamped, by reason.", "reason")
+)
+
+// PipelineLines 的 stage 标签值
+const (
+	StageIngested = "ingested"
+	StageInvalid  = "invalid"
+	StageDropped  = "dropped"
+	StageFiltered = "filtered"
+	StageWritten  = "written"
+)
+
+// GeoIPLookups 的 result 标签值
+const (
+	GeoResultCacheHit = "cache_hit"
+	GeoResultFound    = "found"
+	GeoResultNotFound = "not_found"
+	GeoResultError    = "error"
+)
+
+// AnonymizedAddrs 的 result 标签值
+const (
+	AnonApplied = "anonymized"
+	AnonExempt  = "exempt"
+)
+
+// SamplingAnomalies 的 reason 标签值
+const (
+	SamplingRateOutOfRange = "rate_out_of_range" // 每条流的 SAMPLING_RATE 超出上限，回退到固定采样率
+	SamplingSaturated      = "saturated"         // 放大后的 PACKETS/BYTES 超出 int64，按上限截断
+)
+
+// 上传与诊断结果标签值
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultSkipped = "skipped"
+	ResultEmpty   = "empty"
+)
+
+var protoNames = map[int]string{
+	1:   "icmp",
+	2:   "igmp",
+	6:   "tcp",
+	17:  "udp",
+	47:  "gre",
+	50:  "esp",
+	51:  "ah",
+	58:  "ipv6-icmp",
+	89:  "ospf",
+	132: "sctp",
+}
+
+// ProtoNumber 返回协议名对应的 IP 协议号
+func ProtoNumber(name string) (int, bool) {
+	for num, n := range protoNames {
+		if n == name {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
+// ProtoName 返回 IP 协议号对应的名称，未知协议返回数字
+func ProtoName(proto int) string {
+	if name, ok := protoNames[proto]; ok {
+		return name
+	}
+	return strconv.Itoa(proto)
+}
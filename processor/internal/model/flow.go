@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// 11 列 CSV 的列下标
+// SRC_IP,DST_IP,SRC_PORT,DST_PORT,TCP_FLAGS,PROTOCOL,TOS,TIMESTAMP_MIN,TIMESTAMP_MAX,PACKETS,BYTES
+const (
+	ColSrcIP = iota
+	ColDstIP
+	ColSrcPort
+	ColDstPort
+	ColTCPFlags
+	ColProto
+	ColTOS
+	ColTimestampMin
+	ColTimestampMax
+	ColPackets
+	ColBytes
+	NumColumns
+)
+
+// BaseColumns 11 列 CSV 的列名（与 nfacctd 表头一致）
+var BaseColumns = []string{
+	"SRC_IP", "DST_IP", "SRC_PORT", "DST_PORT", "TCP_FLAGS", "PROTOCOL", "TOS",
+	"TIMESTAMP_MIN", "TIMESTAMP_MAX", "PACKETS", "BYTES",
+}
+
+// Flow 一条已校验的流记录：保留原始列（输出时原样写回），并解析出常用字段；
+// 各处理阶段追加的列依次放在 Extra 中
+type Flow struct {
+	Fields   []string
+	SrcIP    netip.Addr
+	DstIP    netip.Addr
+	SrcPort  uint16
+	DstPort  uint16
+	TCPFlags uint8
+	Proto    uint8
+	TOS      uint8
+	Packets  uint64
+	Bytes    uint64
+	Extra    []string
+}
+
+// ParseFlow 解析一条已通过 validator.ValidateLine 校验的数据行
+func ParseFlow(line string) (*Flow, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) != NumColumns {
+		return nil, fmt.Errorf("列数为 %d，应为 %d", len(fields), NumColumns)
+	}
+	f := &Flow{Fields: fields}
+	var err error
+	if f.SrcIP, err = netip.ParseAddr(strings.TrimSpace(fields[ColSrcIP])); err != nil {
+		return nil, fmt.Errorf("SRC_IP: %w", err)
+	}
+	if f.DstIP, err = netip.ParseAddr(strings.TrimSpace(fields[ColDstIP])); err != nil {
+		return nil, fmt.Errorf("DST_IP: %w", err)
+	}
+	ints := []struct {
+		col  int
+		bits int
+		dst  func(uint64)
+	}{
+		{ColSrcPort, 16, func(v uint64) { f.SrcPort = uint16(v) }},
+		{ColDstPort, 16, func(v uint64) { f.DstPort = uint16(v) }},
+		{ColTCPFlags, 8, func(v uint64) { f.TCPFlags = uint8(v) }},
+		{ColProto, 8, func(v uint64) { f.Proto = uint8(v) }},
+		{ColTOS, 8, func(v uint64) { f.TOS = uint8(v) }},
+		{ColPackets, 64, func(v uint64) { f.Packets = v }},
+		{ColBytes, 64, func(v uint64) { f.Bytes = v }},
+	}
+	for _, c := range ints {
+		v, err := strconv.ParseUint(strings.TrimSpace(fields[c.col]), 10, c.bits)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 列: %w", c.col+1, err)
+		}
+		c.dst(v)
+	}
+	return f, nil
+}
+
+// Scale 按采样率放大 PACKETS/BYTES（同时更新原始列）；结果超出 int64 时按 math.MaxInt64 截断并返回 true，
+// 保证下游按有符号整数解析的统计不会溢出
+func (f *Flow) Scale(rate uint64) (saturated bool) {
+	if rate <= 1 {
+		return false
+	}
+	var sp, sb bool
+	f.Packets, sp = mulSaturated(f.Packets, rate)
+	f.Bytes, sb = mulSaturated(f.Bytes, rate)
+	f.Fields[ColPackets] = strconv.FormatUint(f.Packets, 10)
+	f.Fields[ColBytes] = strconv.FormatUint(f.Bytes, 10)
+	return sp || sb
+}
+
+func mulSaturated(v, rate uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(v, rate)
+	if hi != 0 || lo > math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	return lo, false
+}
+
+// Line 序列化为 CSV 行：原始列在前，追加列在后
+func (f *Flow) Line() string {
+	if len(f.Extra) == 0 {
+		return strings.Join(f.Fields, ",")
+	}
+	var b strings.Builder
+	for i, v := range f.Fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(v)
+	}
+	for _, v := range f.Extra {
+		b.WriteByte(',')
+		b.WriteString(v)
+	}
+	return b.String()
+}
@@ -0,0 +1,234 @@
+package otlp
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pmacct/processor/internal/metrics"
+)
+
+// OTLP/JSON 编码结构（opentelemetry-proto 的 JSON 映射，int64 以字符串表示）
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// ---- metrics ----
+
+type metricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Sum         *sum       `json:"sum,omitempty"`
+	Gauge       *gauge     `json:"gauge,omitempty"`
+	Histogram   *histogram `json:"histogram,omitempty"`
+}
+
+// aggregationTemporalityCumulative 累计值（与 Prometheus 计数器语义一致）
+const aggregationTemporalityCumulative = 2
+
+type sum struct {
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type histogram struct {
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+	DataPoints             []histogramDataPoint `json:"dataPoints"`
+}
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+}
+
+// ---- traces ----
+
+type tracesRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+// spanKindInternal 进程内部操作
+const spanKindInternal = 1
+
+// 状态码
+const (
+	statusCodeOK    = 1
+	statusCodeError = 2
+)
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            spanStatus `json:"status"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func stringAttr(k, v string) keyValue {
+	return keyValue{Key: k, Value: anyValue{StringValue: &v}}
+}
+
+// toAttributes 转换属性表，按 key 排序保证输出稳定
+func toAttributes(attrs map[string]interface{}) []keyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]keyValue, 0, len(keys))
+	for _, k := range keys {
+		var v anyValue
+		switch x := attrs[k].(type) {
+		case string:
+			v.StringValue = &x
+		case int:
+			s := strconv.Itoa(x)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(x, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &x
+		case bool:
+			v.BoolValue = &x
+		default:
+			continue
+		}
+		out = append(out, keyValue{Key: k, Value: v})
+	}
+	return out
+}
+
+func labelAttributes(labels map[string]string) []keyValue {
+	attrs := make(map[string]interface{}, len(labels))
+	for k, v := range labels {
+		attrs[k] = v
+	}
+	return toAttributes(attrs)
+}
+
+// encodeMetrics 将指标快照转换为 OTLP 指标
+func encodeMetrics(families []metrics.Family, start, now time.Time) []metric {
+	startNs, nowNs := unixNano(start), unixNano(now)
+	out := make([]metric, 0, len(families))
+	for _, f := range families {
+		m := metric{Name: f.Name, Description: f.Help}
+		switch f.Kind {
+		case metrics.KindCounter:
+			m.Sum = &sum{AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+			for _, s := range f.Samples {
+				m.Sum.DataPoints = append(m.Sum.DataPoints, numberDataPoint{
+					Attributes:        labelAttributes(s.Labels),
+					StartTimeUnixNano: startNs,
+					TimeUnixNano:      nowNs,
+					AsDouble:          s.Value,
+				})
+			}
+		case metrics.KindGauge:
+			m.Gauge = &gauge{}
+			for _, s := range f.Samples {
+				m.Gauge.DataPoints = append(m.Gauge.DataPoints, numberDataPoint{
+					Attributes:        labelAttributes(s.Labels),
+					StartTimeUnixNano: startNs,
+					TimeUnixNano:      nowNs,
+					AsDouble:          s.Value,
+				})
+			}
+		case metrics.KindHistogram:
+			m.Histogram = &histogram{AggregationTemporality: aggregationTemporalityCumulative}
+			for _, s := range f.Samples {
+				counts := make([]string, len(s.Buckets))
+				for i, c := range s.Buckets {
+					counts[i] = strconv.FormatUint(c, 10)
+				}
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, histogramDataPoint{
+					Attributes:        labelAttributes(s.Labels),
+					StartTimeUnixNano: startNs,
+					TimeUnixNano:      nowNs,
+					Count:             strconv.FormatUint(s.Count, 10),
+					Sum:               s.Sum,
+					BucketCounts:      counts,
+					ExplicitBounds:    s.Bounds,
+				})
+			}
+		default:
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
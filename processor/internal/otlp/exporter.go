@@ -0,0 +1,160 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/host"
+	"github.com/pmacct/processor/internal/metrics"
+)
+
+// spanFlushInterval span 批量导出周期
+const spanFlushInterval = 5 * time.Second
+
+// scopeName 导出数据的 instrumentation scope
+const scopeName = "github.com/pmacct/processor"
+
+// Exporter 通过 OTLP/HTTP（JSON 编码）导出指标与文件生命周期 trace
+type Exporter struct {
+	cfg       config.OTLPConfig
+	client    *http.Client
+	resource  resource
+	version   string
+	startTime time.Time
+
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// NewExporter 创建导出器（未配置 endpoint 时返回 nil）
+func NewExporter(cfg config.OTLPConfig, version, instanceID string) *Exporter {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	hostname := host.FQDN()
+	if instanceID == "" {
+		instanceID = hostname
+	}
+	e := &Exporter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second},
+		resource: resource{Attributes: []keyValue{
+			stringAttr("service.name", cfg.ServiceName),
+			stringAttr("service.version", version),
+			stringAttr("service.instance.id", instanceID),
+			stringAttr("host.name", hostname),
+		}},
+		version:   version,
+		startTime: time.Now(),
+		stopChan:  make(chan struct{}),
+		doneChan:  make(chan struct{}),
+	}
+	if cfg.TracesEnabled {
+		enableTracing(instanceID)
+	}
+	return e
+}
+
+// Start 在后台 goroutine 中周期性导出
+func (e *Exporter) Start() {
+	go e.run()
+}
+
+// Stop 停止导出，返回前会把剩余的 span 与最新指标导出一次
+func (e *Exporter) Stop() {
+	close(e.stopChan)
+	<-e.doneChan
+}
+
+func (e *Exporter) run() {
+	defer close(e.doneChan)
+	ctx := context.Background()
+
+	metricTicker := time.NewTicker(time.Duration(e.cfg.IntervalSec) * time.Second)
+	defer metricTicker.Stop()
+	spanTicker := time.NewTicker(spanFlushInterval)
+	defer spanTicker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			e.exportSpans(ctx)
+			e.exportMetrics(ctx)
+			return
+		case <-metricTicker.C:
+			e.exportMetrics(ctx)
+		case <-spanTicker.C:
+			e.exportSpans(ctx)
+		}
+	}
+}
+
+func (e *Exporter) exportMetrics(ctx context.Context) {
+	if !e.cfg.MetricsEnabled {
+		return
+	}
+	ms := encodeMetrics(metrics.Gather(), e.startTime, time.Now())
+	if len(ms) == 0 {
+		return
+	}
+	req := metricsRequest{ResourceMetrics: []resourceMetrics{{
+		Resource:     e.resource,
+		ScopeMetrics: []scopeMetrics{{Scope: scope{Name: scopeName, Version: e.version}, Metrics: ms}},
+	}}}
+	if err := e.post(ctx, "/v1/metrics", req); err != nil {
+		slog.Warn("OTLP 指标导出失败", "endpoint", e.cfg.Endpoint, "err", err)
+	}
+}
+
+func (e *Exporter) exportSpans(ctx context.Context) {
+	if !e.cfg.TracesEnabled {
+		return
+	}
+	spans, dropped := takeSpans()
+	if dropped > 0 {
+		slog.Warn("OTLP span 缓冲已满，丢弃最旧的 span", "count", dropped)
+	}
+	if len(spans) == 0 {
+		return
+	}
+	req := tracesRequest{ResourceSpans: []resourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName, Version: e.version}, Spans: spans}},
+	}}}
+	if err := e.post(ctx, "/v1/traces", req); err != nil {
+		// trace 仅用于排查，导出失败直接丢弃，避免无限堆积
+		slog.Warn("OTLP trace 导出失败", "endpoint", e.cfg.Endpoint, "spans", len(spans), "err", err)
+	}
+}
+
+func (e *Exporter) post(ctx context.Context, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP 接收端返回 %s", resp.Status)
+	}
+	return nil
+}
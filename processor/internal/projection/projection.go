@@ -0,0 +1,145 @@
+// Package projection 按管道配置的输出列对流记录做重排、裁剪、重命名与派生
+package projection
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/model"
+)
+
+// Projection 已编译的输出列
+type Projection struct {
+	names  []string
+	values []func(f *model.Flow) string
+}
+
+// New 按输出列配置编译投影；available 为投影前的全部列名（11 列及各处理步骤追加的列），loc 为 iso 所用时区
+func New(cols []config.OutputColumn, available []string, loc *time.Location) (*Projection, error) {
+	index := make(map[string]int, len(available))
+	for i, name := range available {
+		index[strings.ToLower(name)] = i
+	}
+	p := &Projection{}
+	for _, c := range cols {
+		var src func(f *model.Flow) string
+		if c.Source != "" {
+			i, ok := index[strings.ToLower(c.Source)]
+			if !ok {
+				return nil, fmt.Errorf("输出列 %s: 源列 %s 不存在（可用列: %s）", c.Name, c.Source, strings.Join(available, ","))
+			}
+			src = column(i)
+		}
+		var fn func(f *model.Flow) string
+		switch c.Func {
+		case "":
+			fn = src
+		case config.ColumnFuncDuration:
+			fn = duration
+		case config.ColumnFuncISO:
+			fn = func(f *model.Flow) string { return isoTime(src(f), loc) }
+		case config.ColumnFuncTCPFlags:
+			fn = func(f *model.Flow) string { return tcpFlags(src(f)) }
+		case config.ColumnFuncProtoName:
+			fn = func(f *model.Flow) string { return protoName(src(f)) }
+		default:
+			return nil, fmt.Errorf("输出列 %s: 未知的函数 %s", c.Name, c.Func)
+		}
+		p.names = append(p.names, c.Name)
+		p.values = append(p.values, fn)
+	}
+	return p, nil
+}
+
+// Columns 输出列名
+func (p *Projection) Columns() []string {
+	return p.names
+}
+
+// Line 按输出列序列化为 CSV 行
+func (p *Projection) Line(f *model.Flow) string {
+	var b strings.Builder
+	for i, v := range p.values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(v(f))
+	}
+	return b.String()
+}
+
+// column 取第 i 列：原始 11 列在前，追加列在后
+func column(i int) func(f *model.Flow) string {
+	return func(f *model.Flow) string {
+		if i < len(f.Fields) {
+			return f.Fields[i]
+		}
+		if j := i - len(f.Fields); j < len(f.Extra) {
+			return f.Extra[j]
+		}
+		return ""
+	}
+}
+
+// duration TIMESTAMP_MAX - TIMESTAMP_MIN（秒，带小数的时间戳保留小数）
+func duration(f *model.Flow) string {
+	start, err1 := strconv.ParseFloat(strings.TrimSpace(f.Fields[model.ColTimestampMin]), 64)
+	end, err2 := strconv.ParseFloat(strings.TrimSpace(f.Fields[model.ColTimestampMax]), 64)
+	if err1 != nil || err2 != nil {
+		return ""
+	}
+	if end < start {
+		return "0"
+	}
+	return strconv.FormatFloat(end-start, 'f', -1, 64)
+}
+
+// isoTime Unix 秒（可带小数）转为 RFC 3339，无法解析时输出空值
+func isoTime(v string, loc *time.Location) string {
+	sec, frac, _ := strings.Cut(strings.TrimSpace(v), ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return ""
+	}
+	var nsec int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		n, err := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return ""
+		}
+		nsec = n
+	}
+	return time.Unix(s, nsec).In(loc).Format(time.RFC3339Nano)
+}
+
+var tcpFlagNames = []string{"FIN", "SYN", "RST", "PSH", "ACK", "URG", "ECE", "CWR"}
+
+// tcpFlags TCP 标志位按位解码，如 18 -> SYN|ACK；无标志位时输出空值
+func tcpFlags(v string) string {
+	n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 8)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for i, name := range tcpFlagNames {
+		if n&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+func protoName(v string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return v
+	}
+	return metrics.ProtoName(n)
+}
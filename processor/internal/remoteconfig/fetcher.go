@@ -0,0 +1,300 @@
+package remoteconfig
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmacct/processor/internal/config"
+)
+
+// maxConfigBytes 远程配置响应体上限
+const maxConfigBytes = 1 << 20
+
+// SignatureHeader 远程配置签名头，取值为 sha256=<hex(HMAC-SHA256(key, body))>
+const SignatureHeader = "X-Config-Signature"
+
+// ErrRestartRequired 新配置校验通过，但修改了需重启才能生效的配置项；该版本仍写入缓存，下次启动时生效
+var ErrRestartRequired = errors.New("需重启进程才能生效")
+
+// cacheFile 最近一次成功生效（或校验通过、待重启生效）的远程配置，重启后在首次拉取成功前使用
+type cacheFile struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Format       string    `json:"format,omitempty"`
+	Content      []byte    `json:"content"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+// Status 远程配置拉取状态（管理接口展示）
+type Status struct {
+	URL         string    `json:"url"`
+	ETag        string    `json:"etag,omitempty"`
+	LastFetch   time.Time `json:"last_fetch,omitempty"`
+	LastApplied time.Time `json:"last_applied,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	// PendingETag 已缓存、待重启后生效的版本
+	PendingETag string `json:"pending_etag,omitempty"`
+}
+
+// Fetcher 以 ETag / If-Modified-Since 条件请求轮询远程配置
+type Fetcher struct {
+	cfg       config.RemoteConfig
+	client    *http.Client
+	token     string
+	key       []byte
+	cachePath string
+
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+	status       Status
+}
+
+// NewFetcher 创建拉取器，token 与签名密钥在此读取
+func NewFetcher(cfg config.RemoteConfig, dataDir string) (*Fetcher, error) {
+	dir := filepath.Join(dataDir, "remoteconfig")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建远程配置缓存目录失败: %w", err)
+	}
+	f := &Fetcher{
+		cfg:       cfg,
+		client:    &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second},
+		cachePath: filepath.Join(dir, "cache.json"),
+		status:    Status{URL: cfg.URL},
+	}
+	if cfg.TokenFile != "" {
+		token, err := readSecretFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 processor_remote_config_token_file 失败: %w", err)
+		}
+		f.token = token
+	}
+	if cfg.SigningKeyFile != "" {
+		key, err := readSecretFile(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 processor_remote_config_signing_key_file 失败: %w", err)
+		}
+		f.key = []byte(key)
+	}
+	return f, nil
+}
+
+// Cached 读取本地缓存的远程配置（不存在或损坏时返回 nil），并以其 ETag 作为首次条件请求的依据
+func (f *Fetcher) Cached() *config.Overlay {
+	raw, err := os.ReadFile(f.cachePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("读取远程配置缓存失败", "path", f.cachePath, "err", err)
+		}
+		return nil
+	}
+	var c cacheFile
+	if err := json.Unmarshal(raw, &c); err != nil {
+		slog.Warn("远程配置缓存已损坏，忽略", "path", f.cachePath, "err", err)
+		return nil
+	}
+	f.mu.Lock()
+	f.etag, f.lastModified = c.ETag, c.LastModified
+	f.status.ETag = c.ETag
+	f.mu.Unlock()
+	return &config.Overlay{Content: c.Content, Format: c.Format}
+}
+
+// Run 立即拉取一次，之后按间隔轮询；拉取到新配置时调用 apply，生效后写入缓存
+func (f *Fetcher) Run(done <-chan struct{}, apply func(*config.Overlay) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	ticker := time.NewTicker(time.Duration(f.cfg.IntervalSec) * time.Second)
+	defer ticker.Stop()
+	for {
+		f.poll(ctx, apply)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Fetcher) poll(ctx context.Context, apply func(*config.Overlay) error) {
+	o, validators, err := f.fetch(ctx)
+	f.mu.Lock()
+	f.status.LastFetch = time.Now()
+	f.mu.Unlock()
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("拉取远程配置失败", "url", f.cfg.URL, "err", err)
+			f.setError(err)
+		}
+		return
+	}
+	if o == nil {
+		// 304：配置未变化
+		return
+	}
+
+	// 记录新版本的 ETag：即使本次校验失败也不再重复应用同一版本，等待服务端更新
+	f.mu.Lock()
+	f.etag, f.lastModified = validators.etag, validators.lastModified
+	f.status.ETag = validators.etag
+	f.mu.Unlock()
+
+	if err := apply(o); err != nil {
+		if errors.Is(err, ErrRestartRequired) {
+			// 写入缓存，重启时 Cached 读到的即为该版本
+			if err := f.saveCache(o, validators); err != nil {
+				slog.Warn("写入远程配置缓存失败", "path", f.cachePath, "err", err)
+			}
+			slog.Warn("远程配置需重启后生效，已缓存，当前继续使用原配置", "etag", validators.etag, "err", err)
+			f.mu.Lock()
+			f.status.PendingETag = validators.etag
+			f.status.LastError = err.Error()
+			f.mu.Unlock()
+			return
+		}
+		slog.Error("远程配置未生效，继续使用原配置", "etag", validators.etag, "err", err)
+		f.setError(err)
+		return
+	}
+	if err := f.saveCache(o, validators); err != nil {
+		slog.Warn("写入远程配置缓存失败", "path", f.cachePath, "err", err)
+	}
+	f.mu.Lock()
+	f.status.LastApplied = time.Now()
+	f.status.LastError = ""
+	f.status.PendingETag = ""
+	f.mu.Unlock()
+	slog.Info("远程配置已生效", "etag", validators.etag)
+}
+
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// fetch 发起条件请求；未变化时返回 nil
+func (f *Fetcher) fetch(ctx context.Context) (*config.Overlay, validators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.cfg.URL, nil)
+	if err != nil {
+		return nil, validators{}, err
+	}
+	f.mu.Lock()
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	if f.lastModified != "" {
+		req.Header.Set("If-Modified-Since", f.lastModified)
+	}
+	f.mu.Unlock()
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, validators{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, validators{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, validators{}, fmt.Errorf("服务端返回 %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigBytes+1))
+	if err != nil {
+		return nil, validators{}, err
+	}
+	if len(body) > maxConfigBytes {
+		return nil, validators{}, fmt.Errorf("远程配置超过 %d 字节", maxConfigBytes)
+	}
+	if err := f.verify(body, resp.Header.Get(SignatureHeader)); err != nil {
+		return nil, validators{}, err
+	}
+	v := validators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	return &config.Overlay{Content: body, Format: config.FormatFromContentType(resp.Header.Get("Content-Type"))}, v, nil
+}
+
+// verify 配置了签名密钥时校验响应签名
+func (f *Fetcher) verify(body []byte, header string) error {
+	if len(f.key) == 0 {
+		return nil
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return fmt.Errorf("远程配置缺少 %s 签名", SignatureHeader)
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("远程配置签名格式错误: %w", err)
+	}
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("远程配置签名校验失败")
+	}
+	return nil
+}
+
+func (f *Fetcher) saveCache(o *config.Overlay, v validators) error {
+	raw, err := json.Marshal(cacheFile{
+		ETag:         v.etag,
+		LastModified: v.lastModified,
+		Format:       o.Format,
+		Content:      o.Content,
+		FetchedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	tmp := f.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.cachePath)
+}
+
+func (f *Fetcher) setError(err error) {
+	f.mu.Lock()
+	f.status.LastError = err.Error()
+	f.mu.Unlock()
+}
+
+// Status 返回当前拉取状态
+func (f *Fetcher) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+func readSecretFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimSpace(string(raw))
+	if v == "" {
+		return "", fmt.Errorf("%s 内容为空", path)
+	}
+	return v, nil
+}
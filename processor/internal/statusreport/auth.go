@@ -0,0 +1,119 @@
+package statusreport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmacct/processor/internal/config"
+)
+
+// 签名相关请求头
+const (
+	headerSignature          = "X-Signature"
+	headerSignatureTimestamp = "X-Signature-Timestamp"
+)
+
+// authenticator 为上报请求附加 Bearer token 与 HMAC 签名
+type authenticator struct {
+	token   string
+	hmacKey []byte
+}
+
+// newAuthenticator 从文件读取上报凭据，未配置的项保持为空
+func newAuthenticator(cfg config.StatusReportConfig) (*authenticator, error) {
+	a := &authenticator{}
+	if cfg.TokenFile != "" {
+		token, err := readSecretFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 processor_status_report_token_file 失败: %w", err)
+		}
+		a.token = token
+	}
+	if cfg.HMACKeyFile != "" {
+		key, err := readSecretFile(cfg.HMACKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 processor_status_report_hmac_key_file 失败: %w", err)
+		}
+		a.hmacKey = []byte(key)
+	}
+	return a, nil
+}
+
+// apply 设置认证头；签名内容为 "<unix 秒>.<body>"，服务端可据时间戳拒绝重放
+func (a *authenticator) apply(req *http.Request, body []byte, now time.Time) {
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	if len(a.hmacKey) > 0 {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(headerSignatureTimestamp, ts)
+		req.Header.Set(headerSignature, "sha256="+signBody(a.hmacKey, ts, body))
+	}
+}
+
+func (a *authenticator) enabled() bool {
+	return a.token != "" || len(a.hmacKey) > 0
+}
+
+func signBody(key []byte, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newHTTPClient 按配置构造上报客户端（可选客户端证书与自定义 CA）
+func newHTTPClient(cfg config.StatusReportConfig) (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if cfg.TLSCertFile == "" && cfg.TLSCAFile == "" {
+		return client, nil
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载状态上报客户端证书失败: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 processor_status_report_tls_ca_file 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("processor_status_report_tls_ca_file 中没有有效的 PEM 证书: %s", cfg.TLSCAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	client.Transport = transport
+	return client, nil
+}
+
+// readSecretFile 读取凭据文件并去除首尾空白，空文件视为错误
+func readSecretFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimSpace(string(raw))
+	if v == "" {
+		return "", fmt.Errorf("文件为空: %s", path)
+	}
+	return v, nil
+}
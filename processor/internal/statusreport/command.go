@@ -0,0 +1,290 @@
+package statusreport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSeenCommands 持久化的已处理指令 ID 数量上限（用于去重）
+const maxSeenCommands = 256
+
+// rejectLogInterval 签名无效指令的告警日志最小间隔，期间的其余指令只计数
+const rejectLogInterval = time.Minute
+
+// 指令回执状态
+const (
+	ackApplied  = "applied"
+	ackRejected = "rejected"
+)
+
+// Command 服务端在上报响应中下发的指令
+type Command struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"`
+	Value     string `json:"value,omitempty"`
+	IssuedAt  int64  `json:"issuedAt"`
+	Signature string `json:"signature"`
+}
+
+// CommandFunc 执行指令，返回生效后的结果描述
+type CommandFunc func(value string) (string, error)
+
+// commandAck 指令回执，随下一次状态报告上报
+type commandAck struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"`
+	Status    string `json:"status"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+	AppliedAt int64  `json:"appliedAt"`
+}
+
+// commandResponse 上报响应体中的指令部分
+type commandResponse struct {
+	Commands []Command `json:"commands"`
+}
+
+// commandProcessor 校验并执行服务端指令，记录审计日志与回执
+type commandProcessor struct {
+	key      []byte
+	uuid     string
+	maxAge   time.Duration
+	handlers map[string]CommandFunc
+
+	seenPath string
+	seen     []string
+	audit    *auditLog
+
+	mu   sync.Mutex
+	acks []commandAck
+
+	lastRejectLog time.Time
+	suppressed    int
+}
+
+func newCommandProcessor(key []byte, uuid string, maxAge time.Duration, stateDir, auditPath string, handlers map[string]CommandFunc) (*commandProcessor, error) {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建指令状态目录失败: %w", err)
+	}
+	cp := &commandProcessor{
+		key:      key,
+		uuid:     uuid,
+		maxAge:   maxAge,
+		handlers: handlers,
+		seenPath: filepath.Join(stateDir, "commands_seen.json"),
+		audit:    &auditLog{path: auditPath},
+	}
+	if raw, err := os.ReadFile(cp.seenPath); err == nil {
+		_ = json.Unmarshal(raw, &cp.seen)
+	}
+	return cp, nil
+}
+
+// handleResponse 解析响应体并依次执行其中的指令
+func (cp *commandProcessor) handleResponse(body []byte) {
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 || body[0] != '{' {
+		return
+	}
+	var resp commandResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		slog.Warn("解析上报响应中的指令失败", "err", err)
+		return
+	}
+	for _, cmd := range resp.Commands {
+		cp.apply(cmd, time.Now())
+	}
+}
+
+func (cp *commandProcessor) apply(cmd Command, now time.Time) {
+	ack := commandAck{ID: cmd.ID, Action: cmd.Action, AppliedAt: now.Unix()}
+
+	if err := cp.verify(cmd, now); err != nil {
+		if errors.Is(err, errDuplicateCommand) {
+			return
+		}
+		if errors.Is(err, errBadSignature) {
+			// 未通过签名校验的指令来源不可信：不写审计、不回执，只记录限频告警
+			cp.logRejected(cmd, err, now)
+			return
+		}
+		// 时间校验失败的指令不记入去重列表，避免重放的旧 ID 占用合法指令
+		ack.Status = ackRejected
+		ack.Error = err.Error()
+		cp.finish(cmd, ack, false)
+		return
+	}
+
+	fn, ok := cp.handlers[cmd.Action]
+	if !ok {
+		ack.Status = ackRejected
+		ack.Error = "不支持的指令: " + cmd.Action
+		cp.finish(cmd, ack, true)
+		return
+	}
+	result, err := fn(cmd.Value)
+	if err != nil {
+		ack.Status = ackRejected
+		ack.Error = err.Error()
+	} else {
+		ack.Status = ackApplied
+		ack.Result = result
+	}
+	cp.finish(cmd, ack, true)
+}
+
+var (
+	errDuplicateCommand = errors.New("指令已处理")
+	errBadSignature     = errors.New("指令签名无效")
+)
+
+// logRejected 签名无效的指令每 rejectLogInterval 最多告警一次，附带期间被丢弃的条数
+func (cp *commandProcessor) logRejected(cmd Command, err error, now time.Time) {
+	if now.Sub(cp.lastRejectLog) < rejectLogInterval {
+		cp.suppressed++
+		return
+	}
+	slog.Warn("丢弃签名无效的服务端指令", "id", cmd.ID, "action", cmd.Action, "err", err, "suppressed", cp.suppressed)
+	cp.lastRejectLog = now
+	cp.suppressed = 0
+}
+
+// verify 校验签名、签发时间与是否重复
+func (cp *commandProcessor) verify(cmd Command, now time.Time) error {
+	if cmd.ID == "" || cmd.Action == "" {
+		return fmt.Errorf("%w: 缺少 id 或 action", errBadSignature)
+	}
+	expected := signCommand(cp.key, cp.uuid, cmd)
+	got := strings.TrimPrefix(cmd.Signature, "sha256=")
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(got))) {
+		return errBadSignature
+	}
+	skew := now.Sub(time.Unix(cmd.IssuedAt, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > cp.maxAge {
+		return fmt.Errorf("指令签发时间超出允许范围: %s", skew.Truncate(time.Second))
+	}
+	for _, id := range cp.seen {
+		if id == cmd.ID {
+			return errDuplicateCommand
+		}
+	}
+	return nil
+}
+
+// finish 记录审计日志与回执，remember 为 true 时记入去重列表
+func (cp *commandProcessor) finish(cmd Command, ack commandAck, remember bool) {
+	if ack.Status == ackApplied {
+		slog.Info("已执行服务端指令", "id", cmd.ID, "action", cmd.Action, "value", cmd.Value, "result", ack.Result)
+	} else {
+		slog.Warn("拒绝服务端指令", "id", cmd.ID, "action", cmd.Action, "err", ack.Error)
+	}
+	if err := cp.audit.write(cmd, ack); err != nil {
+		slog.Error("写入指令审计日志失败", "path", cp.audit.path, "err", err)
+	}
+
+	if remember {
+		cp.remember(cmd.ID)
+	}
+
+	cp.mu.Lock()
+	cp.acks = append(cp.acks, ack)
+	cp.mu.Unlock()
+}
+
+// remember 记录已处理的指令 ID 并持久化，重启后仍可去重
+func (cp *commandProcessor) remember(id string) {
+	cp.seen = append(cp.seen, id)
+	if len(cp.seen) > maxSeenCommands {
+		cp.seen = cp.seen[len(cp.seen)-maxSeenCommands:]
+	}
+	b, err := json.Marshal(cp.seen)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(cp.seenPath, b, 0644); err != nil {
+		slog.Warn("保存已处理指令列表失败", "err", err)
+	}
+}
+
+// carryAcks 配置重载时接管旧处理器中尚未上报的回执
+func (cp *commandProcessor) carryAcks(old *commandProcessor) {
+	acks := old.pendingAcks()
+	cp.mu.Lock()
+	cp.acks = append(acks, cp.acks...)
+	cp.mu.Unlock()
+}
+
+// pendingAcks 返回待上报的回执（不清空）
+func (cp *commandProcessor) pendingAcks() []commandAck {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return append([]commandAck(nil), cp.acks...)
+}
+
+// dropAcks 回执已随报告入队后移除前 n 条
+func (cp *commandProcessor) dropAcks(n int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if n > len(cp.acks) {
+		n = len(cp.acks)
+	}
+	cp.acks = cp.acks[n:]
+}
+
+// signCommand 指令签名：HMAC-SHA256(key, uuid \n id \n action \n value \n issuedAt) 的十六进制；
+// 签名包含目标实例的 uuid，发给某一实例的指令不能被转发给共用密钥的其他实例执行
+func signCommand(key []byte, uuid string, cmd Command) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{uuid, cmd.ID, cmd.Action, cmd.Value, strconv.FormatInt(cmd.IssuedAt, 10)}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditLog 以 JSON Lines 追加记录每条指令的处理结果
+type auditLog struct {
+	path string
+}
+
+func (a *auditLog) write(cmd Command, ack commandAck) error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return err
+	}
+	rec := map[string]interface{}{
+		"time":     time.Unix(ack.AppliedAt, 0).Format(time.RFC3339),
+		"id":       cmd.ID,
+		"action":   cmd.Action,
+		"value":    cmd.Value,
+		"issuedAt": cmd.IssuedAt,
+		"status":   ack.Status,
+	}
+	if ack.Result != "" {
+		rec["result"] = ack.Result
+	}
+	if ack.Error != "" {
+		rec["error"] = ack.Error
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,79 @@
+package statusreport
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/pmacct/processor/internal/host"
+)
+
+// monitoredProcs 上报中需要给出运行状态的进程
+var monitoredProcs = []string{"pmacctd", "nfacctd"}
+
+// Health 管道健康信息，由调用方汇总后通过 SetHealthFunc 提供
+type Health struct {
+	Version    string
+	ConfigHash string
+
+	Lines     LineStats
+	Upload    UploadStats
+	Protocols map[string]int64 // 协议名 -> 流数
+	Services  map[string]int64 // 服务名 -> 流数
+	DNS       DNSStats
+}
+
+// LineStats 输入行处理统计（累计值）
+type LineStats struct {
+	Ingested int64 `json:"ingested"`
+	Invalid  int64 `json:"invalid"`
+	Dropped  int64 `json:"dropped"`
+	Written  int64 `json:"written"`
+}
+
+// UploadStats 上传积压与最近成功时间
+type UploadStats struct {
+	BacklogFiles int       `json:"backlogFiles"`
+	BacklogBytes int64     `json:"backlogBytes"`
+	LastSuccess  time.Time `json:"-"`
+}
+
+// DNSStats DNS 流量统计（累计值，取自服务识别中的 dns）
+type DNSStats struct {
+	Flows int64 `json:"flows"`
+	Total int64 `json:"totalFlows"`
+}
+
+// diskStats 数据目录所在文件系统的容量
+type diskStats struct {
+	Path       string `json:"path"`
+	FreeBytes  uint64 `json:"freeBytes"`
+	TotalBytes uint64 `json:"totalBytes"`
+}
+
+// procState 单个进程的运行状态
+type procState struct {
+	Running bool  `json:"running"`
+	Pids    []int `json:"pids,omitempty"`
+}
+
+func readDiskStats(path string) (diskStats, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return diskStats{Path: path}, err
+	}
+	bsize := uint64(st.Bsize)
+	return diskStats{
+		Path:       path,
+		FreeBytes:  st.Bavail * bsize,
+		TotalBytes: st.Blocks * bsize,
+	}, nil
+}
+
+func readProcStates() map[string]procState {
+	states := make(map[string]procState, len(monitoredProcs))
+	for _, name := range monitoredProcs {
+		pids := host.PidsByName(name)
+		states[name] = procState{Running: len(pids) > 0, Pids: pids}
+	}
+	return states
+}
@@ -0,0 +1,108 @@
+package statusreport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diskQueue 状态上报的本地持久队列：每条报告一个文件，文件名为序号，按序号顺序投递
+type diskQueue struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+}
+
+type queuedReport struct {
+	seq  uint64
+	path string
+	size int64
+	mod  time.Time
+}
+
+func newDiskQueue(dir string, maxBytes int64, maxAge time.Duration) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建上报队列目录失败: %w", err)
+	}
+	return &diskQueue{dir: dir, maxBytes: maxBytes, maxAge: maxAge}, nil
+}
+
+// nextSeq 读取并递增持久化的序号（重启后继续递增，便于服务端发现缺口）
+func (q *diskQueue) nextSeq() (uint64, error) {
+	path := filepath.Join(q.dir, "seq")
+	var seq uint64
+	if data, err := os.ReadFile(path); err == nil {
+		seq, _ = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	}
+	seq++
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(seq, 10)), 0644); err != nil {
+		return 0, err
+	}
+	return seq, os.Rename(tmp, path)
+}
+
+// push 写入一条报告
+func (q *diskQueue) push(seq uint64, body []byte) error {
+	name := fmt.Sprintf("%020d.json", seq)
+	tmp := filepath.Join(q.dir, name+".tmp")
+	if err := os.WriteFile(tmp, body, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(q.dir, name))
+}
+
+// list 按序号升序列出队列中的报告
+func (q *diskQueue) list() ([]queuedReport, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []queuedReport
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, queuedReport{seq: seq, path: filepath.Join(q.dir, name), size: info.Size(), mod: info.ModTime()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].seq < out[j].seq })
+	return out, nil
+}
+
+// trim 按容量与时长上限丢弃最旧的报告，返回丢弃条数
+func (q *diskQueue) trim(now time.Time) int {
+	items, err := q.list()
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, it := range items {
+		total += it.size
+	}
+	dropped := 0
+	for _, it := range items {
+		tooOld := q.maxAge > 0 && now.Sub(it.mod) > q.maxAge
+		overSize := q.maxBytes > 0 && total > q.maxBytes
+		if !tooOld && !overSize {
+			break
+		}
+		if err := os.Remove(it.path); err == nil {
+			dropped++
+			total -= it.size
+		}
+	}
+	return dropped
+}
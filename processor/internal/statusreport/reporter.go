@@ -3,7 +3,9 @@ package statusreport
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -19,8 +21,10 @@ import (
 
 // Reporter 负责聚合流量统计并定期上报
 type Reporter struct {
-	cfg       config.StatusReportConfig
-	client    *http.Client
+	// 可热更新的配置与依赖，仅在 Run goroutine 中读写
+	*settings
+	reload chan *settings
+
 	startTime time.Time
 
 	totalPkts  atomic.Int64
@@ -30,29 +34,142 @@ type Reporter struct {
 	lastPkts      int64
 	lastBytes     int64
 	lastTimestamp time.Time
-	uuid          string
+
+	backoff time.Duration
+
+	dataDir  string
+	healthFn func() Health
+	handlers map[string]CommandFunc
+}
+
+// settings 由配置派生的上报参数与依赖
+type settings struct {
+	cfg    config.StatusReportConfig
+	client *http.Client
+	auth   *authenticator
+	uuid   string
+
+	// 上报失败时的本地队列
+	queue *diskQueue
+
+	// 服务端下发指令（未启用时为 nil）
+	commands *commandProcessor
 }
 
+const (
+	minRetryBackoff = 5 * time.Second
+	maxRetryBackoff = 10 * time.Minute
+
+	// maxResponseBytes 上报响应体读取上限（用于解析下发指令）
+	maxResponseBytes = 1 << 20
+)
+
 // NewReporter 创建 Reporter（未启用时返回 nil, nil）
-func NewReporter(cfg config.StatusReportConfig) (*Reporter, error) {
+func NewReporter(cfg config.StatusReportConfig, dataDir string) (*Reporter, error) {
 	if !cfg.Enabled {
 		return nil, nil
 	}
 
-	uuid := strings.TrimSpace(cfg.UUID)
-	if uuid == "" {
-		uuid = host.FQDN()
+	handlers := make(map[string]CommandFunc)
+	st, err := newSettings(cfg, dataDir, handlers)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Reporter{
-		cfg:           cfg,
-		client:        &http.Client{Timeout: 10 * time.Second},
+		settings:      st,
+		reload:        make(chan *settings, 1),
 		startTime:     time.Now(),
 		lastTimestamp: time.Now(),
-		uuid:          uuid,
+		dataDir:       dataDir,
+		handlers:      handlers,
+	}, nil
+}
+
+func newSettings(cfg config.StatusReportConfig, dataDir string, handlers map[string]CommandFunc) (*settings, error) {
+	uuid := strings.TrimSpace(cfg.UUID)
+	if uuid == "" {
+		uuid = host.FQDN()
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if auth.enabled() && strings.HasPrefix(strings.ToLower(cfg.URL), "http://") {
+		slog.Warn("状态上报使用明文 HTTP，token/签名可能被窃听", "url", cfg.URL)
+	}
+
+	queue, err := newDiskQueue(
+		filepath.Join(dataDir, "statusreport", "queue"),
+		int64(cfg.QueueMaxMB)*1024*1024,
+		time.Duration(cfg.QueueMaxAgeSec)*time.Second,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var commands *commandProcessor
+	if cfg.CommandsEnabled {
+		key, err := readSecretFile(cfg.CommandKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取指令签名密钥失败: %w", err)
+		}
+		auditPath := cfg.AuditLogPath
+		if auditPath == "" {
+			auditPath = filepath.Join(dataDir, "statusreport", "audit.log")
+		}
+		commands, err = newCommandProcessor([]byte(key), uuid, time.Duration(cfg.CommandMaxAgeSec)*time.Second,
+			filepath.Join(dataDir, "statusreport"), auditPath, handlers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &settings{
+		cfg:      cfg,
+		client:   client,
+		auth:     auth,
+		uuid:     uuid,
+		queue:    queue,
+		commands: commands,
 	}, nil
 }
 
+// Reload 按新配置重建上报参数（凭据文件会重新读取），校验失败时返回错误且保持原配置；
+// 累计统计、序号与离线队列不受影响，新配置在 Run 的下一次循环中生效
+func (r *Reporter) Reload(cfg config.StatusReportConfig) error {
+	st, err := newSettings(cfg, r.dataDir, r.handlers)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case r.reload <- st:
+			return nil
+		default:
+		}
+		select {
+		case <-r.reload:
+		default:
+		}
+	}
+}
+
+// HandleCommand 注册服务端指令处理函数，需在 Run 之前调用
+func (r *Reporter) HandleCommand(action string, fn CommandFunc) {
+	r.handlers[action] = fn
+}
+
+// SetHealthFunc 设置管道健康信息来源（仅 schema v2 报文使用），需在 Run 之前调用
+func (r *Reporter) SetHealthFunc(fn func() Health) {
+	r.healthFn = fn
+}
+
 // Add 累加一次包/字节统计
 func (r *Reporter) Add(pkts, bytes int64) {
 	if r == nil {
@@ -69,6 +186,8 @@ func (r *Reporter) Run(ctxDone <-chan struct{}) {
 	}
 	ticker := time.NewTicker(time.Duration(r.cfg.IntervalSec) * time.Second)
 	defer ticker.Stop()
+	retry := time.NewTimer(0)
+	defer retry.Stop()
 
 	for {
 		select {
@@ -76,10 +195,113 @@ func (r *Reporter) Run(ctxDone <-chan struct{}) {
 			return
 		case <-ticker.C:
 			r.reportOnce()
+			r.scheduleRetry(retry, r.flushQueue())
+		case <-retry.C:
+			r.scheduleRetry(retry, r.flushQueue())
+		case st := <-r.reload:
+			if r.commands != nil && st.commands != nil {
+				st.commands.carryAcks(r.commands)
+			}
+			r.settings = st
+			ticker.Reset(time.Duration(st.cfg.IntervalSec) * time.Second)
+			slog.Info("状态上报配置已更新", "url", st.cfg.URL, "interval_sec", st.cfg.IntervalSec)
 		}
 	}
 }
 
+// scheduleRetry 投递失败时按指数退避安排重试；成功则重置退避
+func (r *Reporter) scheduleRetry(retry *time.Timer, ok bool) {
+	if ok {
+		r.backoff = 0
+		return
+	}
+	if r.backoff == 0 {
+		r.backoff = minRetryBackoff
+	} else if r.backoff *= 2; r.backoff > maxRetryBackoff {
+		r.backoff = maxRetryBackoff
+	}
+	if !retry.Stop() {
+		select {
+		case <-retry.C:
+		default:
+		}
+	}
+	retry.Reset(r.backoff)
+}
+
+// flushQueue 按序号顺序投递队列中的报告，遇到失败即停止（保证顺序），全部投递返回 true
+func (r *Reporter) flushQueue() bool {
+	if dropped := r.queue.trim(time.Now()); dropped > 0 {
+		slog.Warn("状态上报队列超出上限，丢弃最旧报告", "count", dropped)
+	}
+	items, err := r.queue.list()
+	if err != nil {
+		slog.Error("读取状态上报队列失败", "err", err)
+		return false
+	}
+	for i, it := range items {
+		body, err := os.ReadFile(it.path)
+		if err != nil {
+			slog.Warn("读取排队的状态上报失败，跳过", "seq", it.seq, "err", err)
+			_ = os.Remove(it.path)
+			continue
+		}
+		resp, err := r.send(body)
+		if err != nil {
+			if errors.Is(err, errReportRejected) {
+				// 服务端明确拒绝（4xx），重试无意义
+				slog.Warn("状态上报被服务端拒绝，丢弃", "seq", it.seq, "err", err)
+				_ = os.Remove(it.path)
+				continue
+			}
+			slog.Error("状态上报失败，已加入本地队列", "seq", it.seq, "pending", len(items)-i, "err", err)
+			return false
+		}
+		_ = os.Remove(it.path)
+		if r.commands != nil {
+			r.commands.handleResponse(resp)
+		}
+		if i > 0 {
+			slog.Info("状态上报补发成功", "seq", it.seq)
+		}
+	}
+	return true
+}
+
+// errReportRejected 服务端返回 4xx（401/403/408/429 除外），报告不再重试
+var errReportRejected = errors.New("状态上报被拒绝")
+
+// send 发送一条报告，成功时返回响应体
+func (r *Reporter) send(body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("状态上报请求创建失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r.auth.apply(req, body, time.Now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300:
+		return respBody, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		// 认证失败多为凭据配置问题，保留报告等待凭据修正后补发
+		return nil, fmt.Errorf("状态上报认证失败: %s", resp.Status)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: %s", errReportRejected, resp.Status)
+	default:
+		return nil, fmt.Errorf("状态上报返回非 2xx: %s", resp.Status)
+	}
+}
+
 func (r *Reporter) reportOnce() {
 	now := time.Now()
 	totalPkts := r.totalPkts.Load()
@@ -102,33 +324,24 @@ func (r *Reporter) reportOnce() {
 		runSecs = 1
 	}
 
-	payload := map[string]interface{}{
-		"curRcvPkts":    deltaPkts,
-		"curRcvBytes":   deltaBytes,
-		"curPkts":       deltaPkts,
-		"curBytes":      deltaBytes,
-		"curAvgRcvPps":  float64(deltaPkts) / elapsedWindow,
-		"curAvgRcvBps":  float64(deltaBytes) / elapsedWindow,
-		"curAvgPps":     float64(deltaPkts) / elapsedWindow,
-		"curAvgBps":     float64(deltaBytes) / elapsedWindow,
-		"uuid":          r.uuid,
-		"runSecs":       int64(runSecs),
-		"totalRcvPkts":  totalPkts,
-		"totalRcvBytes": totalBytes,
-		"totalPkts":     totalPkts,
-		"totalBytes":    totalBytes,
-		"totalAvgRcvPps": func() float64 {
-			return float64(totalPkts) / runSecs
-		}(),
-		"totalAvgRcvBps": func() float64 {
-			return float64(totalBytes) / runSecs
-		}(),
-		"totalAvgPps": func() float64 {
-			return float64(totalPkts) / runSecs
-		}(),
-		"totalAvgBps": func() float64 {
-			return float64(totalBytes) / runSecs
-		}(),
+	seq, err := r.queue.nextSeq()
+	if err != nil {
+		slog.Error("状态上报序号生成失败", "err", err)
+		return
+	}
+
+	var payload map[string]interface{}
+	if r.cfg.SchemaVersion == config.StatusSchemaV1 {
+		payload = r.legacyPayload(deltaPkts, deltaBytes, totalPkts, totalBytes, elapsedWindow, runSecs)
+	} else {
+		payload = r.payloadV2(now, deltaPkts, deltaBytes, totalPkts, totalBytes, elapsedWindow, runSecs)
+	}
+	payload["seq"] = seq
+	var acks []commandAck
+	if r.commands != nil {
+		if acks = r.commands.pendingAcks(); len(acks) > 0 {
+			payload["acks"] = acks
+		}
 	}
 
 	b, err := json.Marshal(payload)
@@ -144,24 +357,92 @@ func (r *Reporter) reportOnce() {
 		}
 	}
 
-	// HTTP 上报（失败不影响落盘）
-	req, err := http.NewRequest(http.MethodPost, r.cfg.URL, bytes.NewReader(b))
-	if err != nil {
-		slog.Error("状态上报请求创建失败", "err", err)
+	// 先入队再投递，HTTP 失败时保留在本地队列等待补发（失败不影响落盘）
+	if err := r.queue.push(seq, b); err != nil {
+		slog.Error("状态上报入队失败", "seq", seq, "err", err)
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if len(acks) > 0 {
+		r.commands.dropAcks(len(acks))
+	}
+}
 
-	resp, err := r.client.Do(req)
+// legacyPayload 旧版扁平报文（schema v1），保留给尚未升级的中心端
+func (r *Reporter) legacyPayload(deltaPkts, deltaBytes, totalPkts, totalBytes int64, elapsedWindow, runSecs float64) map[string]interface{} {
+	return map[string]interface{}{
+		"curRcvPkts":     deltaPkts,
+		"curRcvBytes":    deltaBytes,
+		"curPkts":        deltaPkts,
+		"curBytes":       deltaBytes,
+		"curAvgRcvPps":   float64(deltaPkts) / elapsedWindow,
+		"curAvgRcvBps":   float64(deltaBytes) / elapsedWindow,
+		"curAvgPps":      float64(deltaPkts) / elapsedWindow,
+		"curAvgBps":      float64(deltaBytes) / elapsedWindow,
+		"uuid":           r.uuid,
+		"runSecs":        int64(runSecs),
+		"totalRcvPkts":   totalPkts,
+		"totalRcvBytes":  totalBytes,
+		"totalPkts":      totalPkts,
+		"totalBytes":     totalBytes,
+		"totalAvgRcvPps": float64(totalPkts) / runSecs,
+		"totalAvgRcvBps": float64(totalBytes) / runSecs,
+		"totalAvgPps":    float64(totalPkts) / runSecs,
+		"totalAvgBps":    float64(totalBytes) / runSecs,
+	}
+}
+
+// payloadV2 结构化报文（schema v2）：流量去重后按组归类，并附带管道健康信息
+func (r *Reporter) payloadV2(now time.Time, deltaPkts, deltaBytes, totalPkts, totalBytes int64, elapsedWindow, runSecs float64) map[string]interface{} {
+	payload := map[string]interface{}{
+		"schemaVersion": config.StatusSchemaV2,
+		"uuid":          r.uuid,
+		"timestamp":     now.Unix(),
+		"runSecs":       int64(runSecs),
+		"traffic": map[string]interface{}{
+			"curPkts":     deltaPkts,
+			"curBytes":    deltaBytes,
+			"curAvgPps":   float64(deltaPkts) / elapsedWindow,
+			"curAvgBps":   float64(deltaBytes) / elapsedWindow,
+			"totalPkts":   totalPkts,
+			"totalBytes":  totalBytes,
+			"totalAvgPps": float64(totalPkts) / runSecs,
+			"totalAvgBps": float64(totalBytes) / runSecs,
+		},
+		"processes": readProcStates(),
+	}
+
+	disk, err := readDiskStats(r.dataDir)
 	if err != nil {
-		slog.Error("状态上报失败", "err", err)
-		return
+		slog.Warn("读取数据盘容量失败", "path", r.dataDir, "err", err)
 	}
-	defer resp.Body.Close()
+	payload["disk"] = disk
 
-	if resp.StatusCode >= 300 {
-		slog.Warn("状态上报返回非 2xx", "status", resp.Status)
+	if r.healthFn == nil {
+		return payload
+	}
+	h := r.healthFn()
+	payload["version"] = h.Version
+	payload["configHash"] = h.ConfigHash
+	payload["lines"] = h.Lines
+	upload := map[string]interface{}{
+		"backlogFiles": h.Upload.BacklogFiles,
+		"backlogBytes": h.Upload.BacklogBytes,
+		"lastSuccess":  int64(0),
+	}
+	if !h.Upload.LastSuccess.IsZero() {
+		upload["lastSuccess"] = h.Upload.LastSuccess.Unix()
+	}
+	payload["upload"] = upload
+	if h.Protocols == nil {
+		h.Protocols = map[string]int64{}
+	}
+	payload["protocols"] = h.Protocols
+	if h.Services == nil {
+		h.Services = map[string]int64{}
 	}
+	payload["services"] = h.Services
+	payload["dns"] = h.DNS
+	return payload
 }
 
 // appendToFile 追加写入文件并做简单大小轮转
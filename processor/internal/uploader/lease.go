@@ -0,0 +1,201 @@
+package uploader
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+
+	"github.com/pmacct/processor/internal/config"
+)
+
+// errLeaseHeld 表示同名文件正由其他实例上传
+var errLeaseHeld = errors.New("远端租约被其他实例持有")
+
+// remoteLease 远端租约文件内容：<instance_id> <unix_ts>
+type remoteLease struct {
+	owner   string
+	renewed time.Time
+}
+
+// leasePath 租约文件与最终文件同目录同名，所有实例共用
+func leasePath(remotePath string) string {
+	return remotePath + ".lock"
+}
+
+// tempIDSep 临时文件名中实例标识前的分隔符，不会出现在 sanitizeInstanceID 的结果中
+const tempIDSep = "~"
+
+// tempSuffix 本实例的远端临时文件后缀
+func (u *Uploader) tempSuffix() string {
+	return tempIDSep + u.instanceID + ".tmp"
+}
+
+// ownsTempFile 取出临时文件名中最后一个分隔符之后的实例标识，与本实例完全相等才归本实例所有
+func (u *Uploader) ownsTempFile(name string) bool {
+	base, ok := strings.CutSuffix(name, ".tmp")
+	if !ok {
+		return false
+	}
+	i := strings.LastIndex(base, tempIDSep)
+	return i >= 0 && base[i+len(tempIDSep):] == u.instanceID
+}
+
+// persistentInstanceID 读取持久化的实例标识，不存在时生成随机标识并写入
+func persistentInstanceID(path string) string {
+	if data, err := os.ReadFile(path); err == nil {
+		if id := sanitizeInstanceID(string(data)); id != "unknown" {
+			return id
+		}
+	}
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	id := "unknown-" + hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+			slog.Warn("保存实例标识失败", "path", path, "err", err)
+		}
+	}
+	return id
+}
+
+// acquireLease 获取最终文件名的租约；FTP 没有原子创建，写入后回读确认归属
+func (u *Uploader) acquireLease(conn *ftp.ServerConn, remotePath string) error {
+	lockPath := leasePath(remotePath)
+	if lease, err := readLease(conn, lockPath); err == nil {
+		if lease.owner != u.instanceID && time.Since(lease.renewed) < u.leaseTTL {
+			return fmt.Errorf("%w: %s（owner=%s）", errLeaseHeld, remotePath, lease.owner)
+		}
+		if lease.owner != u.instanceID {
+			slog.Warn("远端租约已过期，接管", "file", remotePath, "owner", lease.owner, "renewed", lease.renewed.Format(time.RFC3339))
+		}
+	}
+
+	if err := u.writeLease(conn, lockPath); err != nil {
+		return fmt.Errorf("写入远端租约失败: %w", err)
+	}
+	lease, err := readLease(conn, lockPath)
+	if err != nil {
+		return fmt.Errorf("回读远端租约失败: %w", err)
+	}
+	if lease.owner != u.instanceID {
+		return fmt.Errorf("%w: %s（owner=%s）", errLeaseHeld, remotePath, lease.owner)
+	}
+	return nil
+}
+
+func (u *Uploader) writeLease(conn *ftp.ServerConn, lockPath string) error {
+	content := fmt.Sprintf("%s %d\n", u.instanceID, time.Now().Unix())
+	return conn.Stor(lockPath, strings.NewReader(content))
+}
+
+// keepLease 传输期间每 leaseTTL/3 续约一次，避免大文件或限速上传超过租约有效期被其他实例接管；
+// 上传连接在 Stor 期间被占用，续约另开连接。返回的 stop 结束续约
+func (u *Uploader) keepLease(t config.FTPTarget, remotePath string) (stop func()) {
+	interval := u.leaseTTL / 3
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		lockPath := leasePath(remotePath)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := u.renewLease(t, lockPath); err != nil {
+				slog.Warn("续约远端租约失败", "file", remotePath, "err", err)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// renewLease 租约仍归本实例时刷新时间戳；已被其他实例接管则不覆盖
+func (u *Uploader) renewLease(t config.FTPTarget, lockPath string) error {
+	conn, err := u.connect(t)
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+	lease, err := readLease(conn, lockPath)
+	if err != nil {
+		return err
+	}
+	if lease.owner != u.instanceID {
+		return fmt.Errorf("%w（owner=%s）", errLeaseHeld, lease.owner)
+	}
+	return u.writeLease(conn, lockPath)
+}
+
+// releaseLease 释放本实例持有的租约
+func (u *Uploader) releaseLease(conn *ftp.ServerConn, remotePath string) {
+	lockPath := leasePath(remotePath)
+	lease, err := readLease(conn, lockPath)
+	if err != nil || lease.owner != u.instanceID {
+		return
+	}
+	if err := conn.Delete(lockPath); err != nil {
+		slog.Warn("删除远端租约失败", "file", lockPath, "err", err)
+	}
+}
+
+func readLease(conn *ftp.ServerConn, lockPath string) (remoteLease, error) {
+	resp, err := conn.Retr(lockPath)
+	if err != nil {
+		return remoteLease{}, err
+	}
+	defer resp.Close()
+	data, err := io.ReadAll(io.LimitReader(resp, 1024))
+	if err != nil {
+		return remoteLease{}, err
+	}
+	fields := strings.Fields(string(bytes.TrimSpace(data)))
+	if len(fields) != 2 {
+		return remoteLease{}, fmt.Errorf("租约格式错误: %q", data)
+	}
+	ts, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return remoteLease{}, fmt.Errorf("租约时间格式错误: %w", err)
+	}
+	return remoteLease{owner: fields[0], renewed: time.Unix(ts, 0)}, nil
+}
+
+// sanitizeInstanceID 实例标识用于远端文件名，仅保留安全字符
+func sanitizeInstanceID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "unknown"
+	}
+	var b strings.Builder
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package uploader
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶限速（字节/秒），进程内所有管道、所有目标共享同一个桶（见 sharedTokenBucket）
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+var (
+	sharedBucketMu sync.Mutex
+	sharedBucket   *tokenBucket
+)
+
+// sharedTokenBucket 返回进程内共用的令牌桶并按 bytesPerSec 调整速率，使限速作用于所有管道之和；
+// bytesPerSec <= 0 表示不限速，返回 nil（各管道的上传配置相同，热加载时会一起更新）
+func sharedTokenBucket(bytesPerSec int64) *tokenBucket {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	sharedBucketMu.Lock()
+	defer sharedBucketMu.Unlock()
+	if sharedBucket == nil {
+		sharedBucket = newTokenBucket(bytesPerSec)
+	} else {
+		sharedBucket.setRate(bytesPerSec)
+	}
+	return sharedBucket
+}
+
+func newTokenBucket(bytesPerSec int64) *tokenBucket {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	b := &tokenBucket{last: time.Now()}
+	b.setRate(bytesPerSec)
+	b.tokens = b.burst
+	return b
+}
+
+// setRate 调整速率与桶容量（容量至少 32KB），已有令牌不超过新容量
+func (b *tokenBucket) setRate(bytesPerSec int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rate = float64(bytesPerSec)
+	b.burst = b.rate
+	if b.burst < 32*1024 {
+		b.burst = 32 * 1024
+	}
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// maxChunk 单次读取的上限（不超过桶容量）
+func (b *tokenBucket) maxChunk() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.burst)
+}
+
+// wait 消耗 n 个令牌，不足时等待补充
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	deficit := -b.tokens
+	rate := b.rate
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitedReader 包装传给 conn.Stor 的 reader，按令牌桶限速
+type rateLimitedReader struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *tokenBucket
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if max := r.bucket.maxChunk(); len(p) > max {
+		p = p[:max]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.bucket.wait(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
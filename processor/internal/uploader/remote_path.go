@@ -0,0 +1,71 @@
+package uploader
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 远端文件类型（对应路径模板中的 {type}）
+const (
+	fileTypeFlows     = "flows"
+	fileTypeDiag      = "diag"
+	fileTypeErrors    = "errors"
+	fileTypeManifests = "manifests"
+)
+
+// fileTimeRe 匹配文件名中的窗口时间：flows_20060102_150405_000 / diag_host_20060102T150405+0800
+var fileTimeRe = regexp.MustCompile(`(\d{8})[_T](\d{6})`)
+
+// fileType 按文件名判断文件类型
+func fileType(filename string) string {
+	switch {
+	case strings.HasPrefix(filename, "diag_"):
+		return fileTypeDiag
+	case strings.HasPrefix(filename, "errorline"):
+		return fileTypeErrors
+	case strings.Contains(filename, ".manifest."):
+		return fileTypeManifests
+	default:
+		return fileTypeFlows
+	}
+}
+
+// fileWindow 从文件名解析窗口起始时间（文件名中的墙上时间），解析失败时回退到文件修改时间
+func fileWindow(filename string, modTime time.Time) time.Time {
+	m := fileTimeRe.FindStringSubmatch(filename)
+	if m == nil {
+		return modTime
+	}
+	t, err := time.ParseInLocation("20060102150405", m[1]+m[2], modTime.Location())
+	if err != nil {
+		return modTime
+	}
+	return t
+}
+
+// renderRemoteDir 将路径模板渲染为远端目录（拼接在目标目录之下）
+func renderRemoteDir(baseDir, tmpl, host, typ string, window time.Time) string {
+	if tmpl == "" {
+		return baseDir
+	}
+	r := strings.NewReplacer(
+		"{type}", typ,
+		"{host}", sanitizePathSegment(host),
+		"{yyyy}", window.Format("2006"),
+		"{mm}", window.Format("01"),
+		"{dd}", window.Format("02"),
+		"{HH}", window.Format("15"),
+	)
+	return path.Join(baseDir, r.Replace(tmpl))
+}
+
+// sanitizePathSegment 避免主机名等变量引入额外的目录层级
+func sanitizePathSegment(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "unknown"
+	}
+	return strings.ReplaceAll(s, "/", "_")
+}
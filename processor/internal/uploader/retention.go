@@ -0,0 +1,114 @@
+package uploader
+
+import (
+	"errors"
+	"log/slog"
+	"net/textproto"
+	"sort"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+
+	"github.com/pmacct/processor/internal/config"
+)
+
+// retentionInterval 远端保留清理的最小间隔（清理不必随每次上传扫描执行）
+const retentionInterval = time.Hour
+
+// enforceRetention 按保留天数与容量预算删除本实例上传过的远端文件
+func (u *Uploader) enforceRetention(now time.Time) {
+	if !u.retention.Enabled() || now.Sub(u.lastRetention) < retentionInterval {
+		return
+	}
+	u.lastRetention = now
+
+	changed := false
+	for _, t := range u.targets {
+		expired := selectExpired(u.ledger.entries(t.Name), u.retention, now)
+		if len(expired) == 0 {
+			continue
+		}
+		if u.retention.DryRun {
+			for _, e := range expired {
+				slog.Info("远端保留清理（dry-run）：将删除", "target", t.Name, "file", e.RemotePath, "size", e.Size, "uploaded_at", time.Unix(e.UploadedAt, 0).Format(time.RFC3339))
+			}
+			continue
+		}
+		removed := u.deleteRemoteFiles(t, expired)
+		if len(removed) > 0 {
+			u.ledger.remove(t.Name, removed)
+			changed = true
+			slog.Info("远端保留清理完成", "target", t.Name, "count", len(removed))
+		}
+	}
+	if changed {
+		if err := u.ledger.save(); err != nil {
+			slog.Warn("保存上传记录失败", "err", err)
+		}
+	}
+}
+
+// deleteRemoteFiles 删除远端文件，返回已不在远端的路径（含本就不存在的）
+func (u *Uploader) deleteRemoteFiles(t config.FTPTarget, entries []ledgerEntry) map[string]bool {
+	removed := make(map[string]bool)
+	conn, err := u.connect(t)
+	if err != nil {
+		slog.Warn("远端保留清理连接失败", "target", t.Name, "err", err)
+		return removed
+	}
+	defer conn.Quit()
+
+	for _, e := range entries {
+		if _, err := conn.FileSize(e.RemotePath); err != nil {
+			if !isNotFound(err) {
+				// 连接中断、权限等其他错误：保留记录，下次清理再试
+				slog.Warn("远端保留清理查询文件失败", "target", t.Name, "file", e.RemotePath, "err", err)
+				continue
+			}
+			// 远端已不存在（可能被人工清理），只移除记录
+			removed[e.RemotePath] = true
+			continue
+		}
+		if err := conn.Delete(e.RemotePath); err != nil {
+			slog.Warn("远端保留清理删除失败", "target", t.Name, "file", e.RemotePath, "err", err)
+			continue
+		}
+		removed[e.RemotePath] = true
+		slog.Info("远端保留清理：已删除", "target", t.Name, "file", e.RemotePath, "size", e.Size)
+	}
+	return removed
+}
+
+// isNotFound 判断 FTP 应答是否为 550（文件不存在）
+func isNotFound(err error) bool {
+	var tpErr *textproto.Error
+	return errors.As(err, &tpErr) && tpErr.Code == ftp.StatusFileUnavailable
+}
+
+// selectExpired 选出超过保留天数、以及超出容量预算（从最旧开始）的记录
+func selectExpired(entries []ledgerEntry, r config.RetentionConfig, now time.Time) []ledgerEntry {
+	sorted := make([]ledgerEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].UploadedAt < sorted[j].UploadedAt
+	})
+
+	var total int64
+	for _, e := range sorted {
+		total += e.Size
+	}
+	budget := int64(r.MaxMB) * 1024 * 1024
+	cutoff := now.AddDate(0, 0, -r.Days).Unix()
+
+	var expired []ledgerEntry
+	for _, e := range sorted {
+		tooOld := r.Days > 0 && e.UploadedAt < cutoff
+		overBudget := budget > 0 && total > budget
+		if !tooOld && !overBudget {
+			break
+		}
+		expired = append(expired, e)
+		total -= e.Size
+	}
+	return expired
+}
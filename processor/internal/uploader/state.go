@@ -0,0 +1,214 @@
+package uploader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// deliveryRecord 记录文件送达某个目标的结果
+type deliveryRecord struct {
+	RemotePath string `json:"remote_path"`
+	Size       int64  `json:"size"`
+	UploadedAt int64  `json:"uploaded_at"`
+}
+
+// deliveryState 按目标记录本地文件的送达情况（文件名 -> 目标名 -> 记录）
+type deliveryState struct {
+	path  string
+	Files map[string]map[string]deliveryRecord `json:"files"`
+}
+
+func loadDeliveryState(path string) *deliveryState {
+	s := &deliveryState{path: path, Files: map[string]map[string]deliveryRecord{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if err := json.Unmarshal(data, s); err != nil || s.Files == nil {
+		s.Files = map[string]map[string]deliveryRecord{}
+	}
+	return s
+}
+
+func (s *deliveryState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	blob, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *deliveryState) delivered(file, target string) bool {
+	_, ok := s.Files[file][target]
+	return ok
+}
+
+func (s *deliveryState) markDelivered(file, target string, rec deliveryRecord) {
+	m, ok := s.Files[file]
+	if !ok {
+		m = map[string]deliveryRecord{}
+		s.Files[file] = m
+	}
+	m[target] = rec
+}
+
+func (s *deliveryState) forget(file string) {
+	delete(s.Files, file)
+}
+
+// prune 清理本地已不存在文件的送达记录
+func (s *deliveryState) prune(existing map[string]bool) {
+	for file := range s.Files {
+		if !existing[file] {
+			delete(s.Files, file)
+		}
+	}
+}
+
+// ledgerEntry 本实例上传到远端的文件记录（用于远端保留清理）
+type ledgerEntry struct {
+	RemotePath string `json:"remote_path"`
+	Size       int64  `json:"size"`
+	UploadedAt int64  `json:"uploaded_at"`
+}
+
+// uploadLedger 按目标记录本实例已上传的远端文件（目标名 -> 远端路径 -> 记录）
+type uploadLedger struct {
+	path    string
+	Targets map[string]map[string]ledgerEntry `json:"targets"`
+}
+
+func loadUploadLedger(path string) *uploadLedger {
+	l := &uploadLedger{path: path, Targets: map[string]map[string]ledgerEntry{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return l
+	}
+	if err := json.Unmarshal(data, l); err == nil && l.Targets != nil {
+		return l
+	}
+	// 兼容旧格式：每个目标一个记录列表
+	var legacy struct {
+		Targets map[string][]ledgerEntry `json:"targets"`
+	}
+	l.Targets = map[string]map[string]ledgerEntry{}
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		for target, entries := range legacy.Targets {
+			for _, e := range entries {
+				l.record(target, e)
+			}
+		}
+	}
+	return l
+}
+
+func (l *uploadLedger) save() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	blob, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// record 记录一次上传，同一远端路径只保留最新记录
+func (l *uploadLedger) record(target string, e ledgerEntry) {
+	m, ok := l.Targets[target]
+	if !ok {
+		m = map[string]ledgerEntry{}
+		l.Targets[target] = m
+	}
+	m[e.RemotePath] = e
+}
+
+// entries 返回目标的全部记录
+func (l *uploadLedger) entries(target string) []ledgerEntry {
+	out := make([]ledgerEntry, 0, len(l.Targets[target]))
+	for _, e := range l.Targets[target] {
+		out = append(out, e)
+	}
+	return out
+}
+
+// remove 删除指定远端路径的记录
+func (l *uploadLedger) remove(target string, remotePaths map[string]bool) {
+	m := l.Targets[target]
+	for p := range remotePaths {
+		delete(m, p)
+	}
+}
+
+// remoteDirs 按目标记录本实例写过临时文件的远端目录及最近一次上传时间（Unix 秒），
+// 重启后仍能清理分区目录中的残留临时文件
+type remoteDirs struct {
+	path    string
+	dirty   bool
+	Targets map[string]map[string]int64 `json:"targets"`
+}
+
+func loadRemoteDirs(path string) *remoteDirs {
+	d := &remoteDirs{path: path, Targets: map[string]map[string]int64{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return d
+	}
+	if err := json.Unmarshal(data, d); err != nil || d.Targets == nil {
+		d.Targets = map[string]map[string]int64{}
+	}
+	return d
+}
+
+func (d *remoteDirs) save() error {
+	if !d.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return err
+	}
+	blob, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, d.path); err != nil {
+		return err
+	}
+	d.dirty = false
+	return nil
+}
+
+// touch 记录一次向该目录的上传
+func (d *remoteDirs) touch(target, dir string, now int64) {
+	m, ok := d.Targets[target]
+	if !ok {
+		m = map[string]int64{}
+		d.Targets[target] = m
+	}
+	m[dir] = now
+	d.dirty = true
+}
+
+// forget 目录中已无本实例的临时文件且长期未上传时不再扫描
+func (d *remoteDirs) forget(target, dir string) {
+	if _, ok := d.Targets[target][dir]; ok {
+		delete(d.Targets[target], dir)
+		d.dirty = true
+	}
+}
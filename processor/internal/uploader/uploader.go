@@ -2,45 +2,114 @@ package uploader
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jlaffaye/ftp"
+
+	"github.com/pmacct/processor/internal/config"
+	"github.com/pmacct/processor/internal/host"
+	"github.com/pmacct/processor/internal/metrics"
+	"github.com/pmacct/processor/internal/otlp"
 )
 
+// errTargetUnavailable 表示目标无法连接或登录（本轮扫描内不再尝试该目标）
+var errTargetUnavailable = errors.New("FTP 目标不可用")
+
 // Uploader 负责定时扫描目录并上传文件到 FTP
 type Uploader struct {
 	ctx               context.Context
-	ftpHost           string
-	ftpPort           int
-	ftpUser           string
-	ftpPass           string
-	ftpDir            string
-	ftpTimeoutSec     int // FTP操作超时时间（秒）
+	targets           []config.FTPTarget
+	policy            string
+	pathTemplate      string
+	host              string
+	instanceID        string
+	tempMaxAge        time.Duration
+	leaseTTL          time.Duration
+	bucket            *tokenBucket // 上传限速，nil 表示不限速
+	windows           []config.TimeWindow
+	location          *time.Location
 	dataDir           string
 	uploadIntervalSec int
+	state             *deliveryState
+	ledger            *uploadLedger
+	retention         config.RetentionConfig
+	lastRetention     time.Time
+	ledgerDirty       bool
+	dirCache          map[string]map[string]bool // 目标名 -> 已确认存在的远端目录
+	tempDirs          *remoteDirs                // 写过临时文件的远端目录（持久化）
 	stopChan          chan struct{}
 	doneChan          chan struct{}
+	trigger           chan struct{}
+	interval          chan time.Duration
+	reconfig          chan config.UploadConfig
+
+	statusMu    sync.Mutex
+	lastScan    time.Time
+	lastSuccess time.Time
+	lastErrors  []string
+}
+
+// maxLastErrors 状态中保留的最近上传错误条数
+const maxLastErrors = 20
+
+// Status 上传器运行状态（供管理接口查询）
+type Status struct {
+	Policy      string    `json:"policy"`
+	Targets     []string  `json:"targets"`
+	QueueFiles  []string  `json:"queue_files"`
+	QueueCount  int       `json:"queue_count"`
+	QueueBytes  int64     `json:"queue_bytes"`
+	LastScan    time.Time `json:"last_scan"`
+	LastSuccess time.Time `json:"last_success"`
+	LastErrors  []string  `json:"last_errors"`
+	InWindowNow bool      `json:"in_window_now"`
 }
 
 // NewUploader 创建新的 Uploader
-func NewUploader(ctx context.Context, ftpHost string, ftpPort int, ftpUser, ftpPass, ftpDir string, ftpTimeoutSec int, dataDir string, uploadIntervalSec int) *Uploader {
+func NewUploader(ctx context.Context, cfg config.UploadConfig, dataDir string, uploadIntervalSec int) *Uploader {
+	hostname := host.FQDN()
+	instanceID := cfg.InstanceID
+	if strings.TrimSpace(instanceID) == "" {
+		instanceID = hostname
+	}
+	instanceID = sanitizeInstanceID(instanceID)
+	if instanceID == "unknown" {
+		// 主机名不可用时各实例会共用同一标识，改用数据目录中持久化的随机标识
+		instanceID = persistentInstanceID(filepath.Join(dataDir, "upload", "instance_id"))
+	}
 	return &Uploader{
 		ctx:               ctx,
-		ftpHost:           ftpHost,
-		ftpPort:           ftpPort,
-		ftpUser:           ftpUser,
-		ftpPass:           ftpPass,
-		ftpDir:            ftpDir,
-		ftpTimeoutSec:     ftpTimeoutSec,
+		targets:           cfg.Targets,
+		policy:            cfg.Policy,
+		pathTemplate:      cfg.PathTemplate,
+		host:              hostname,
+		instanceID:        instanceID,
+		tempMaxAge:        time.Duration(cfg.TempMaxAgeSec) * time.Second,
+		leaseTTL:          time.Duration(cfg.LeaseSec) * time.Second,
+		bucket:            sharedTokenBucket(int64(cfg.RateLimitKBps) * 1024),
+		windows:           cfg.Windows,
+		location:          cfg.Location,
 		dataDir:           dataDir,
 		uploadIntervalSec: uploadIntervalSec,
+		state:             loadDeliveryState(filepath.Join(dataDir, "upload", "delivery.json")),
+		ledger:            loadUploadLedger(filepath.Join(dataDir, "upload", "ledger.json")),
+		retention:         cfg.Retention,
+		dirCache:          make(map[string]map[string]bool),
+		tempDirs:          loadRemoteDirs(filepath.Join(dataDir, "upload", "dirs.json")),
 		stopChan:          make(chan struct{}),
 		doneChan:          make(chan struct{}),
+		trigger:           make(chan struct{}, 1),
+		interval:          make(chan time.Duration, 1),
+		reconfig:          make(chan config.UploadConfig, 1),
 	}
 }
 
@@ -55,6 +124,64 @@ func (u *Uploader) Stop() {
 	<-u.doneChan
 }
 
+// TriggerScan 请求立即执行一次扫描上传（执行前的重复请求会合并）
+func (u *Uploader) TriggerScan() {
+	select {
+	case u.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// UpdateConfig 热更新上传目标、凭据与策略等配置（实例标识不变），在两次扫描之间生效
+func (u *Uploader) UpdateConfig(cfg config.UploadConfig) {
+	for {
+		select {
+		case u.reconfig <- cfg:
+			return
+		default:
+		}
+		select {
+		case <-u.reconfig:
+		default:
+		}
+	}
+}
+
+// applyConfig 在 run goroutine 中替换配置；与 Status 等并发读取方通过 statusMu 互斥
+func (u *Uploader) applyConfig(cfg config.UploadConfig) {
+	u.statusMu.Lock()
+	u.targets = cfg.Targets
+	u.policy = cfg.Policy
+	u.pathTemplate = cfg.PathTemplate
+	u.tempMaxAge = time.Duration(cfg.TempMaxAgeSec) * time.Second
+	u.leaseTTL = time.Duration(cfg.LeaseSec) * time.Second
+	u.bucket = sharedTokenBucket(int64(cfg.RateLimitKBps) * 1024)
+	u.windows = cfg.Windows
+	u.location = cfg.Location
+	u.retention = cfg.Retention
+	u.statusMu.Unlock()
+
+	// 目标或路径模板可能已变化，远端目录缓存需重新确认
+	u.dirCache = make(map[string]map[string]bool)
+	slog.Info("上传配置已更新", "targets", len(cfg.Targets), "policy", cfg.Policy)
+}
+
+// SetUploadInterval 运行时调整定时扫描间隔（秒），未生效前的多次调整以最后一次为准
+func (u *Uploader) SetUploadInterval(sec int) {
+	d := time.Duration(sec) * time.Second
+	for {
+		select {
+		case u.interval <- d:
+			return
+		default:
+		}
+		select {
+		case <-u.interval:
+		default:
+		}
+	}
+}
+
 // run 主循环：定时扫描并上传
 func (u *Uploader) run() {
 	defer close(u.doneChan)
@@ -69,6 +196,13 @@ func (u *Uploader) run() {
 		select {
 		case <-ticker.C:
 			u.scanAndUpload()
+		case <-u.trigger:
+			u.scanAndUpload()
+		case d := <-u.interval:
+			ticker.Reset(d)
+			slog.Info("上传扫描间隔已调整", "interval", d.String())
+		case cfg := <-u.reconfig:
+			u.applyConfig(cfg)
 		case <-u.stopChan:
 			return
 		case <-u.ctx.Done():
@@ -78,7 +212,7 @@ func (u *Uploader) run() {
 	}
 }
 
-// scanAndUpload 扫描数据目录并上传所有 .csv.gz 文件
+// scanAndUpload 扫描数据目录，按优先级上传 .csv.gz / .json.gz 文件
 func (u *Uploader) scanAndUpload() {
 	// 检查上下文是否已取消
 	if u.ctx != nil {
@@ -90,45 +224,62 @@ func (u *Uploader) scanAndUpload() {
 		}
 	}
 
-	if err := u.cleanupRemoteTempFiles(); err != nil {
-		slog.Warn("清理远端临时文件失败", "err", err)
+	if !u.inUploadWindow(time.Now()) {
+		slog.Debug("当前不在上传时间窗内，跳过本轮上传")
+		return
 	}
+	u.statusMu.Lock()
+	u.lastScan = time.Now()
+	u.statusMu.Unlock()
 
-	entries, err := os.ReadDir(u.dataDir)
-	if err != nil {
+	for _, t := range u.targets {
+		if err := u.cleanupRemoteTempFiles(t); err != nil {
+			slog.Warn("清理远端临时文件失败", "target", t.Name, "err", err)
+		}
+	}
+
+	if _, err := os.Stat(u.dataDir); err != nil {
 		slog.Error("扫描数据目录失败", "err", err)
 		return
 	}
+	pending, _ := u.pendingFiles()
 
-	var filesToUpload []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if strings.HasSuffix(entry.Name(), ".csv.gz") {
-			filesToUpload = append(filesToUpload, entry.Name())
-		}
+	filesToUpload := make([]string, 0, len(pending))
+	for _, f := range pending {
+		filesToUpload = append(filesToUpload, f.name)
 	}
 
-	logEntries, err := os.ReadDir(u.dataDir)
-	if err == nil {
-		for _, entry := range logEntries {
-			if entry.IsDir() {
-				continue
-			}
-			if strings.HasSuffix(entry.Name(), ".json.gz") {
-				filesToUpload = append(filesToUpload, entry.Name())
+	existing := make(map[string]bool, len(filesToUpload))
+	for _, name := range filesToUpload {
+		existing[name] = true
+	}
+	u.state.prune(existing)
+	defer func() {
+		if err := u.state.save(); err != nil {
+			slog.Warn("保存上传状态失败", "err", err)
+		}
+		if err := u.tempDirs.save(); err != nil {
+			slog.Warn("保存远端目录记录失败", "err", err)
+		}
+		if u.ledgerDirty {
+			if err := u.ledger.save(); err != nil {
+				slog.Warn("保存上传记录失败", "err", err)
+			} else {
+				u.ledgerDirty = false
 			}
 		}
-	}
+		u.enforceRetention(time.Now())
+	}()
 
 	if len(filesToUpload) == 0 {
 		return
 	}
 
-	slog.Info("发现待上传文件", "count", len(filesToUpload))
+	slog.Info("发现待上传文件", "count", len(filesToUpload), "policy", u.policy)
 
-	for _, filename := range filesToUpload {
+	// 本轮扫描中连接失败的目标，后续文件不再尝试
+	down := make(map[string]bool)
+	for i, filename := range filesToUpload {
 		// 检查上下文是否已取消
 		if u.ctx != nil {
 			select {
@@ -138,85 +289,182 @@ func (u *Uploader) scanAndUpload() {
 			default:
 			}
 		}
+		if !u.inUploadWindow(time.Now()) {
+			slog.Info("上传时间窗已结束，剩余文件留待下个时间窗", "remaining", len(filesToUpload)-i)
+			return
+		}
 
 		filePath := filepath.Join(u.dataDir, filename)
-		if err := u.uploadFile(filePath, filename); err != nil {
-			slog.Error("FTP 上传失败", "file", filename, "err", err)
-			// 继续处理下一个文件，不删除失败的文件
+		var done bool
+		if u.policy == config.UploadPolicyReplicate {
+			done = u.deliverReplicate(filePath, filename, down)
+		} else {
+			done = u.deliverFailover(filePath, filename, down)
+		}
+		if !done {
+			// 未送达，不删除文件，等待下次扫描重试
 			continue
 		}
 
-		// 上传成功，删除本地文件
-		if err := os.Remove(filePath); err != nil {
+		// 已送达，删除本地文件
+		err := os.Remove(filePath)
+		otlp.FileSpan(filename, otlp.SpanFileDeleted, time.Now(), time.Now(), err, nil)
+		if err != nil {
 			slog.Error("删除本地文件失败", "file", filename, "err", err)
 		} else {
+			u.state.forget(filename)
 			slog.Info("FTP 上传成功并删除本地文件", "file", filename)
 		}
 	}
 }
 
-// uploadFile 上传单个文件到 FTP
-func (u *Uploader) uploadFile(localPath, filename string) error {
+// deliverFailover 按顺序尝试各目标，任一目标成功即返回 true
+func (u *Uploader) deliverFailover(filePath, filename string, down map[string]bool) bool {
+	for _, t := range u.targets {
+		if u.state.delivered(filename, t.Name) {
+			return true
+		}
+		if down[t.Name] {
+			continue
+		}
+		if u.deliverTo(t, filePath, filename, down) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverReplicate 上传到所有尚未送达的目标，全部送达后返回 true
+func (u *Uploader) deliverReplicate(filePath, filename string, down map[string]bool) bool {
+	all := true
+	for _, t := range u.targets {
+		if u.state.delivered(filename, t.Name) {
+			continue
+		}
+		if down[t.Name] || !u.deliverTo(t, filePath, filename, down) {
+			all = false
+		}
+	}
+	return all
+}
+
+// deliverTo 上传到单个目标并记录送达状态
+func (u *Uploader) deliverTo(t config.FTPTarget, filePath, filename string, down map[string]bool) bool {
+	start := time.Now()
+	remotePath, size, uploaded, err := u.uploadFile(t, filePath, filename)
+	spanAttrs := map[string]interface{}{"upload.target": t.Name, "upload.remote_path": remotePath, "file.size": size}
+	if err != nil {
+		if errors.Is(err, errLeaseHeld) {
+			spanAttrs["upload.result"] = metrics.ResultSkipped
+			otlp.FileSpan(filename, otlp.SpanFileUploaded, start, time.Now(), nil, spanAttrs)
+			metrics.Uploads.Inc(t.Name, metrics.ResultSkipped)
+			slog.Info("同名文件正由其他实例上传，稍后重试", "file", filename, "target", t.Name, "err", err)
+			return false
+		}
+		metrics.Uploads.Inc(t.Name, metrics.ResultFailure)
+		spanAttrs["upload.result"] = metrics.ResultFailure
+		otlp.FileSpan(filename, otlp.SpanFileUploaded, start, time.Now(), err, spanAttrs)
+		u.recordError(fmt.Sprintf("%s %s -> %s: %v", time.Now().Format(time.RFC3339), filename, t.Name, err))
+		if errors.Is(err, errTargetUnavailable) {
+			down[t.Name] = true
+		} else {
+			// 远端目录可能已被外部删除，下次重新确认
+			delete(u.dirCache, t.Name)
+		}
+		slog.Error("FTP 上传失败", "file", filename, "target", t.Name, "err", err)
+		return false
+	}
+	metrics.Uploads.Inc(t.Name, metrics.ResultSuccess)
+	metrics.UploadDuration.Observe(time.Since(start).Seconds(), t.Name)
+	spanAttrs["upload.result"] = metrics.ResultSuccess
+	otlp.FileSpan(filename, otlp.SpanFileUploaded, start, time.Now(), nil, spanAttrs)
+	u.statusMu.Lock()
+	u.lastSuccess = time.Now()
+	u.statusMu.Unlock()
+	now := time.Now().Unix()
+	u.state.markDelivered(filename, t.Name, deliveryRecord{
+		RemotePath: remotePath,
+		Size:       size,
+		UploadedAt: now,
+	})
+	// 只有开启远端保留清理时才需要记录；远端原本就有的同名文件不是本实例上传的，不纳入清理
+	if u.retention.Enabled() && uploaded {
+		u.ledger.record(t.Name, ledgerEntry{RemotePath: remotePath, Size: size, UploadedAt: now})
+		u.ledgerDirty = true
+	}
+	return true
+}
+
+// connect 连接并登录 FTP 目标
+func (u *Uploader) connect(t config.FTPTarget) (*ftp.ServerConn, error) {
+	addr := fmt.Sprintf("%s:%d", t.Host, t.Port)
+	conn, err := ftp.Dial(addr, ftp.DialWithTimeout(time.Duration(t.TimeoutSec)*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 连接 FTP 服务器失败: %v", errTargetUnavailable, err)
+	}
+	if err := conn.Login(t.User, t.Pass); err != nil {
+		conn.Quit()
+		return nil, fmt.Errorf("%w: FTP 登录失败: %v", errTargetUnavailable, err)
+	}
+	return conn, nil
+}
+
+// uploadFile 上传单个文件到 FTP 目标，返回远端路径、文件大小以及是否由本实例实际上传
+// （远端已有同名同大小文件而跳过时为 false）
+func (u *Uploader) uploadFile(t config.FTPTarget, localPath, filename string) (string, int64, bool, error) {
 	// 检查上下文是否已取消
 	if u.ctx != nil {
 		select {
 		case <-u.ctx.Done():
-			return fmt.Errorf("上下文已取消，跳过上传文件: %s", filename)
+			return "", 0, false, fmt.Errorf("上下文已取消，跳过上传文件: %s", filename)
 		default:
 		}
 	}
 
-	slog.Info("准备上传文件", "file", filename)
+	slog.Info("准备上传文件", "file", filename, "target", t.Name)
 
 	// 获取本地文件大小
 	localInfo, err := os.Stat(localPath)
 	if err != nil {
-		return fmt.Errorf("获取本地文件信息失败: %w", err)
+		return "", 0, false, fmt.Errorf("获取本地文件信息失败: %w", err)
 	}
 	localSize := localInfo.Size()
 
-	// 连接 FTP 服务器
-	addr := fmt.Sprintf("%s:%d", u.ftpHost, u.ftpPort)
-	conn, err := ftp.Dial(addr, ftp.DialWithTimeout(time.Duration(u.ftpTimeoutSec)*time.Second))
+	// 连接并登录 FTP 服务器
+	conn, err := u.connect(t)
 	if err != nil {
-		return fmt.Errorf("连接 FTP 服务器失败: %w", err)
+		return "", 0, false, err
 	}
-	defer func() {
-		if conn != nil {
-			conn.Quit()
-		}
-	}()
+	defer conn.Quit()
 
-	// 登录
-	if err := conn.Login(u.ftpUser, u.ftpPass); err != nil {
-		return fmt.Errorf("FTP 登录失败: %w", err)
-	}
-
-	remoteBaseDir, remoteFilename := u.resolveRemotePath(filename)
+	remoteBaseDir, remoteFilename := u.resolveRemotePath(t, filename, localInfo.ModTime())
 
 	// 确保远程目录存在
-	if err := u.ensureRemoteDir(conn, remoteBaseDir); err != nil {
-		return fmt.Errorf("创建远程目录失败: %w", err)
+	if err := u.ensureRemoteDir(conn, t, remoteBaseDir); err != nil {
+		return "", 0, false, fmt.Errorf("创建远程目录失败: %w", err)
 	}
 
-	// 构建远程文件路径（最终文件 + 临时文件）
-	remotePath := remoteBaseDir + "/" + remoteFilename
-	if strings.HasSuffix(remoteBaseDir, "/") {
-		remotePath = remoteBaseDir + remoteFilename
-	}
-	tempName := remoteFilename + ".tmp"
-	remoteTempPath := remoteBaseDir + "/" + tempName
-	if strings.HasSuffix(remoteBaseDir, "/") {
-		remoteTempPath = remoteBaseDir + tempName
+	u.tempDirs.touch(t.Name, remoteBaseDir, time.Now().Unix())
+
+	// 构建远程文件路径（最终文件 + 本实例临时文件）
+	remotePath := joinRemotePath(remoteBaseDir, remoteFilename)
+	remoteTempPath := joinRemotePath(remoteBaseDir, remoteFilename+u.tempSuffix())
+
+	// 获取最终文件名的租约，避免多个实例同时写同名文件
+	if err := u.acquireLease(conn, remotePath); err != nil {
+		return "", 0, false, err
 	}
+	defer u.releaseLease(conn, remotePath)
+	stopRenew := u.keepLease(t, remotePath)
+	defer stopRenew()
 
 	// 检查 FTP 服务器上是否已存在最终文件（避免重复上传）
 	if remoteSize, err := conn.FileSize(remotePath); err == nil {
 		if remoteSize == localSize {
-			slog.Info("远端已存在同名文件且大小一致，跳过上传", "file", filename, "size", localSize)
-			return nil
+			slog.Info("远端已存在同名文件且大小一致，跳过上传", "file", filename, "target", t.Name, "size", localSize)
+			return remotePath, localSize, false, nil
 		}
-		slog.Warn("远端已存在同名文件但大小不一致，将尝试覆盖", "file", filename, "local_size", localSize, "remote_size", remoteSize)
+		slog.Warn("远端已存在同名文件但大小不一致，将尝试覆盖", "file", filename, "target", t.Name, "local_size", localSize, "remote_size", remoteSize)
 		if err := conn.Delete(remotePath); err != nil {
 			slog.Warn("删除远端旧文件失败（将继续尝试上传临时文件）", "file", remotePath, "err", err)
 		}
@@ -233,38 +481,42 @@ func (u *Uploader) uploadFile(localPath, filename string) error {
 	// 打开本地文件
 	file, err := os.Open(localPath)
 	if err != nil {
-		return fmt.Errorf("打开本地文件失败: %w", err)
+		return "", 0, false, fmt.Errorf("打开本地文件失败: %w", err)
 	}
 	defer file.Close()
 
 	// 上传文件到临时路径
-	slog.Info("开始上传临时文件", "file", filename, "remote_temp_path", remoteTempPath, "size", localSize)
-	if err := conn.Stor(remoteTempPath, file); err != nil {
-		return fmt.Errorf("上传临时文件失败: %w", err)
+	slog.Info("开始上传临时文件", "file", filename, "target", t.Name, "remote_temp_path", remoteTempPath, "size", localSize)
+	var reader io.Reader = file
+	if u.bucket != nil {
+		reader = &rateLimitedReader{ctx: u.ctx, r: file, bucket: u.bucket}
+	}
+	if err := conn.Stor(remoteTempPath, reader); err != nil {
+		return "", 0, false, fmt.Errorf("上传临时文件失败: %w", err)
 	}
 
 	// 上传完成后校验大小
 	remoteTempSize, err := conn.FileSize(remoteTempPath)
 	if err != nil {
-		return fmt.Errorf("获取远端临时文件大小失败: %w", err)
+		return "", 0, false, fmt.Errorf("获取远端临时文件大小失败: %w", err)
 	}
 	if remoteTempSize != localSize {
-		return fmt.Errorf("远端临时文件大小不一致: local=%d, remote=%d", localSize, remoteTempSize)
+		return "", 0, false, fmt.Errorf("远端临时文件大小不一致: local=%d, remote=%d", localSize, remoteTempSize)
 	}
 	slog.Info("远端临时文件大小校验通过", "remote_temp_path", remoteTempPath, "size", remoteTempSize)
 
 	// 重命名为最终文件
 	slog.Info("重命名远端临时文件", "from", remoteTempPath, "to", remotePath)
 	if err := conn.Rename(remoteTempPath, remotePath); err != nil {
-		return fmt.Errorf("重命名远端文件失败: %w", err)
+		return "", 0, false, fmt.Errorf("重命名远端文件失败: %w", err)
 	}
-	slog.Info("上传完成", "file", filename, "size", localSize)
+	slog.Info("上传完成", "file", filename, "target", t.Name, "size", localSize)
 
-	return nil
+	return remotePath, localSize, true, nil
 }
 
-// cleanupRemoteTempFiles 清理远端残留临时文件（.tmp）
-func (u *Uploader) cleanupRemoteTempFiles() error {
+// cleanupRemoteTempFiles 清理本实例超龄的远端残留临时文件（~<instance_id>.tmp）
+func (u *Uploader) cleanupRemoteTempFiles(t config.FTPTarget) error {
 	// 检查上下文是否已取消
 	if u.ctx != nil {
 		select {
@@ -274,59 +526,64 @@ func (u *Uploader) cleanupRemoteTempFiles() error {
 		}
 	}
 
-	addr := fmt.Sprintf("%s:%d", u.ftpHost, u.ftpPort)
-	conn, err := ftp.Dial(addr, ftp.DialWithTimeout(time.Duration(u.ftpTimeoutSec)*time.Second))
+	conn, err := u.connect(t)
 	if err != nil {
-		return fmt.Errorf("连接 FTP 服务器失败: %w", err)
+		return err
 	}
-	defer func() {
-		if conn != nil {
-			conn.Quit()
-		}
-	}()
+	defer conn.Quit()
 
-	if err := conn.Login(u.ftpUser, u.ftpPass); err != nil {
-		return fmt.Errorf("FTP 登录失败: %w", err)
+	// 目标根目录 + 本实例写过临时文件的分区目录（含重启前记录的）
+	if err := u.ensureRemoteDir(conn, t, t.Dir); err != nil {
+		return fmt.Errorf("创建远程目录失败: %w", err)
 	}
-
 	cleaned := 0
-	paths := []string{u.ftpDir}
-	for _, dir := range paths {
-		if err := u.ensureRemoteDir(conn, dir); err != nil {
-			return fmt.Errorf("创建远程目录失败: %w", err)
+	paths := []string{t.Dir}
+	for dir := range u.tempDirs.Targets[t.Name] {
+		if dir != t.Dir {
+			paths = append(paths, dir)
 		}
+	}
+	for _, dir := range paths {
 		entries, err := conn.List(dir)
 		if err != nil {
+			if dir != t.Dir && isNotFound(err) {
+				// 分区目录已被外部删除
+				u.tempDirs.forget(t.Name, dir)
+				continue
+			}
 			return fmt.Errorf("列出远端目录失败: %w", err)
 		}
+		remaining := 0
 		for _, entry := range entries {
-			if entry.Type != ftp.EntryTypeFile {
+			if entry.Type != ftp.EntryTypeFile || !u.ownsTempFile(entry.Name) {
 				continue
 			}
-			name := entry.Name
-			if !strings.HasSuffix(name, ".tmp") {
+			// 时间未知时视为超龄（仅限本实例的临时文件）
+			if !entry.Time.IsZero() && time.Since(entry.Time) < u.tempMaxAge {
+				remaining++
 				continue
 			}
-			remotePath := dir + "/" + name
-			if strings.HasSuffix(dir, "/") {
-				remotePath = dir + name
-			}
+			remotePath := joinRemotePath(dir, entry.Name)
 			if err := conn.Delete(remotePath); err != nil {
 				slog.Warn("删除远端临时文件失败", "file", remotePath, "err", err)
+				remaining++
 				continue
 			}
 			cleaned++
 			slog.Info("已清理远端临时文件", "file", remotePath)
 		}
+		if last, ok := u.tempDirs.Targets[t.Name][dir]; ok && remaining == 0 && time.Since(time.Unix(last, 0)) > u.tempMaxAge {
+			u.tempDirs.forget(t.Name, dir)
+		}
 	}
 	if cleaned > 0 {
-		slog.Info("远端临时文件清理完成", "count", cleaned)
+		slog.Info("远端临时文件清理完成", "target", t.Name, "count", cleaned)
 	}
 	return nil
 }
 
-// ensureRemoteDir 确保远程目录存在
-func (u *Uploader) ensureRemoteDir(conn *ftp.ServerConn, dir string) error {
+// ensureRemoteDir 确保远程目录存在（已确认存在的目录按目标缓存，不再重复检查）
+func (u *Uploader) ensureRemoteDir(conn *ftp.ServerConn, t config.FTPTarget, dir string) error {
 	// 检查上下文是否已取消
 	if u.ctx != nil {
 		select {
@@ -336,6 +593,15 @@ func (u *Uploader) ensureRemoteDir(conn *ftp.ServerConn, dir string) error {
 		}
 	}
 
+	cache, ok := u.dirCache[t.Name]
+	if !ok {
+		cache = make(map[string]bool)
+		u.dirCache[t.Name] = cache
+	}
+	if cache[dir] {
+		return nil
+	}
+
 	// 尝试切换到目录，如果失败则创建
 	if err := conn.ChangeDir(dir); err != nil {
 		// 目录不存在，尝试创建
@@ -370,9 +636,170 @@ func (u *Uploader) ensureRemoteDir(conn *ftp.ServerConn, dir string) error {
 			return fmt.Errorf("无法切换到远程目录: %w", err)
 		}
 	}
+	cache[dir] = true
 	return nil
 }
 
-func (u *Uploader) resolveRemotePath(filename string) (string, string) {
-	return u.ftpDir, filename
+// resolveRemotePath 按路径模板计算远端目录，日期与小时取自文件窗口时间
+func (u *Uploader) resolveRemotePath(t config.FTPTarget, filename string, modTime time.Time) (string, string) {
+	dir := renderRemoteDir(t.Dir, u.pathTemplate, u.host, fileType(filename), fileWindow(filename, modTime))
+	return dir, filename
+}
+
+// recordError 记录最近的上传错误
+func (u *Uploader) recordError(msg string) {
+	u.statusMu.Lock()
+	defer u.statusMu.Unlock()
+	u.lastErrors = append(u.lastErrors, msg)
+	if len(u.lastErrors) > maxLastErrors {
+		u.lastErrors = u.lastErrors[len(u.lastErrors)-maxLastErrors:]
+	}
+}
+
+// Status 返回上传器当前状态，队列按上传优先级排序（最多列出 maxFiles 个文件名）
+func (u *Uploader) Status(maxFiles int) Status {
+	pending, size := u.pendingFiles()
+	st := Status{
+		QueueCount: len(pending),
+		QueueBytes: size,
+	}
+	for i, f := range pending {
+		if i >= maxFiles {
+			break
+		}
+		st.QueueFiles = append(st.QueueFiles, f.name)
+	}
+	u.statusMu.Lock()
+	st.Policy = u.policy
+	st.InWindowNow = u.inUploadWindow(time.Now())
+	for _, t := range u.targets {
+		st.Targets = append(st.Targets, t.Name)
+	}
+	st.LastScan = u.lastScan
+	st.LastSuccess = u.lastSuccess
+	st.LastErrors = append([]string(nil), u.lastErrors...)
+	u.statusMu.Unlock()
+	return st
+}
+
+// LastSuccess 返回最近一次成功上传的时间（从未成功时为零值）
+func (u *Uploader) LastSuccess() time.Time {
+	u.statusMu.Lock()
+	defer u.statusMu.Unlock()
+	return u.lastSuccess
+}
+
+// CheckReachable 检查是否至少有一个目标可以连接并登录；timeout 大于 0 时替代各目标的连接超时
+func (u *Uploader) CheckReachable(timeout time.Duration) error {
+	u.statusMu.Lock()
+	targets := u.targets
+	u.statusMu.Unlock()
+
+	var errs []string
+	for _, t := range targets {
+		if timeout > 0 {
+			t.TimeoutSec = int(timeout / time.Second)
+		}
+		conn, err := u.connect(t)
+		if err == nil {
+			conn.Quit()
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", t.Name, err))
+	}
+	return fmt.Errorf("所有上传目标均不可达: %s", strings.Join(errs, "; "))
+}
+
+// pendingFiles 列出待上传文件（按上传优先级排序）及总大小
+func (u *Uploader) pendingFiles() ([]pendingFile, int64) {
+	entries, err := os.ReadDir(u.dataDir)
+	if err != nil {
+		return nil, 0
+	}
+	var pending []pendingFile
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() || !isUploadable(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name := entry.Name()
+		pending = append(pending, pendingFile{
+			name:   name,
+			typ:    fileType(name),
+			window: fileWindow(name, info.ModTime()),
+			size:   info.Size(),
+		})
+		size += info.Size()
+	}
+	sortByPriority(pending)
+	return pending, size
+}
+
+// Backlog 返回数据目录中待上传文件的数量与总大小
+func (u *Uploader) Backlog() (int, int64) {
+	pending, size := u.pendingFiles()
+	return len(pending), size
+}
+
+// isUploadable 是否为需要上传的文件（流量 .csv.gz / 诊断 .json.gz）
+func isUploadable(name string) bool {
+	return strings.HasSuffix(name, ".csv.gz") || strings.HasSuffix(name, ".json.gz")
+}
+
+// inUploadWindow 判断当前是否允许上传（未配置时间窗时始终允许）
+func (u *Uploader) inUploadWindow(now time.Time) bool {
+	if len(u.windows) == 0 {
+		return true
+	}
+	if u.location != nil {
+		now = now.In(u.location)
+	}
+	for _, w := range u.windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingFile 待上传的本地文件
+type pendingFile struct {
+	name   string
+	typ    string
+	window time.Time
+	size   int64
+}
+
+// typePriority 上传优先级：流量数据优先于诊断数据
+var typePriority = map[string]int{
+	fileTypeFlows:     0,
+	fileTypeDiag:      1,
+	fileTypeErrors:    2,
+	fileTypeManifests: 3,
+}
+
+// sortByPriority 按类型优先级排序，同类型内按窗口时间从旧到新
+func sortByPriority(files []pendingFile) {
+	sort.SliceStable(files, func(i, j int) bool {
+		pi, pj := typePriority[files[i].typ], typePriority[files[j].typ]
+		if pi != pj {
+			return pi < pj
+		}
+		if !files[i].window.Equal(files[j].window) {
+			return files[i].window.Before(files[j].window)
+		}
+		return files[i].name < files[j].name
+	})
+}
+
+// joinRemotePath 拼接远端目录与文件名
+func joinRemotePath(dir, name string) string {
+	if strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
 }